@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
+	"strings"
 
+	"faas/internal/shared/infrastructure/auth"
 	"faas/internal/shared/infrastructure/config"
+	"faas/internal/shared/infrastructure/http/middleware"
 	"faas/internal/shared/infrastructure/nats"
+	"faas/internal/shared/infrastructure/ratelimit"
 
 	"github.com/gin-gonic/gin"
 
@@ -70,6 +75,9 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := executionRepo.EnsureIndex(context.Background()); err != nil {
+		log.Fatal("Failed to index executions:", err)
+	}
 
 	objectRepo, err := objRepo.NewNatsObjectRepository(js)
 	if err != nil {
@@ -81,28 +89,126 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// Stream repository
+	scheduleRepo, err := execRepo.NewNatsScheduleRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	archiveRepo, err := execRepo.NewNatsExecutionArchiveRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhookRepo, err := execRepo.NewNatsWebhookRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outputRepo, err := execRepo.NewNatsOutputRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	quotaRepo, err := execRepo.NewNatsQuotaRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	leaseRepo, err := execRepo.NewNatsLeaseRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	workerRepo, err := execRepo.NewNatsWorkerRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cacheRepo, err := execRepo.NewNatsCacheRepository(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rateLimiter, err := ratelimit.NewNatsLimiter(js)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Stream repositories
 	execStreamRepo := execRepo.NewNatsExecutionStreamRepository(js)
+	execLogRepo := execRepo.NewNatsExecutionLogRepository(js)
+	execEventRepo := execRepo.NewNatsExecutionEventRepository(js)
 
 	// Initialize services
-	funcService := funcService.NewFunctionService(functionRepo)
+	funcService := funcService.NewFunctionService(functionRepo, cfg)
 	userService := userService.NewUserService(userRepo, cfg)
-	executionService := execService.NewExecutionService(executionRepo, execStreamRepo, functionRepo, cfg)
+	if err := userService.EnsureAdmin(context.Background()); err != nil {
+		log.Fatal("Failed to set up the admin user:", err)
+	}
+	quotaService := execService.NewQuotaService(quotaRepo, executionRepo, userRepo, functionRepo, cfg)
+	cacheService := execService.NewCacheService(cacheRepo, objectRepo, cfg)
+	executionService := execService.NewExecutionService(executionRepo, execStreamRepo, scheduleRepo, execEventRepo, functionRepo, quotaService, cacheService, cfg)
+	schedulerService := execService.NewSchedulerService(executionRepo, execStreamRepo, scheduleRepo, execEventRepo, quotaService, cfg)
+	retentionService := execService.NewRetentionService(executionRepo, archiveRepo, outputRepo, execLogRepo, functionRepo, cfg)
+	logService := execService.NewLogService(executionRepo, execLogRepo)
+	eventService := execService.NewEventService(executionRepo, execEventRepo, functionRepo)
+	outputService := execService.NewOutputService(executionRepo, outputRepo)
+	webhookService := execService.NewWebhookService(executionRepo, webhookRepo, functionRepo, cfg)
+	workerService := execService.NewWorkerService(executionRepo, execStreamRepo, leaseRepo, workerRepo, execEventRepo, execLogRepo, functionRepo, quotaService, cfg)
+	usageService := execService.NewUsageService(executionRepo)
 	objectService := objService.NewObjectService(objectRepo)
 	secretService := secretService.NewSecretService(secretRepo)
 	// Initialize handlers
 	functionHandler := funcHttp.NewFunctionHandler(funcService)
 	userHandler := userHttp.NewUserHandler(userService)
 	executionHandler := execHttp.NewExecutionHandler(executionService)
+	retentionHandler := execHttp.NewRetentionHandler(retentionService)
+	logHandler := execHttp.NewLogHandler(logService)
+	eventHandler := execHttp.NewEventHandler(eventService)
+	webhookHandler := execHttp.NewWebhookHandler(webhookService)
+	outputHandler := execHttp.NewOutputHandler(outputService)
+	workerHandler := execHttp.NewWorkerHandler(workerService)
+	usageHandler := execHttp.NewUsageHandler(usageService)
 	objectHandler := objHttp.NewObjectHandler(objectService)
 	secretHandler := secretHttp.NewSecretHandler(secretService)
+
+	// Start background services
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go schedulerService.Start(ctx)
+	go retentionService.Start(ctx)
+	go webhookService.Start(ctx)
+	go workerService.Start(ctx)
+	go quotaService.Start(ctx)
+
 	// Initialize Gin
 	r := gin.Default()
+	// Client IPs, which anonymous rate limits key on, are only read from
+	// forwarding headers set by these proxies
+	if err := r.SetTrustedProxies(trustedProxies(cfg.TrustedProxies)); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	rateLimits, err := ratelimit.ParseLimits(cfg.RateLimits)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMITS: %v", err)
+	}
+	invokeTokens := middleware.ScopedSecret{Scope: auth.ScopeInvoke, Secret: cfg.InvokeTokenSecret}
+	r.Use(middleware.RateLimit(rateLimiter, rateLimits, cfg.JWTSecret, invokeTokens))
 
 	// Setup routes
 	funcHttp.SetupFunctionRoutes(r, functionHandler, cfg.JWTSecret)
+	funcHttp.SetupFunctionAdminRoutes(r, functionHandler, cfg.JWTSecret)
 	userHttp.SetupUserRoutes(r, userHandler)
-	execHttp.SetupExecutionRoutes(r, executionHandler, cfg.JWTSecret)
+	userHttp.SetupUserAdminRoutes(r, userHandler, cfg.JWTSecret)
+	execHttp.SetupExecutionRoutes(r, executionHandler, cfg.JWTSecret, cfg.InvokeTokenSecret)
+	execHttp.SetupRetentionRoutes(r, retentionHandler, cfg.JWTSecret)
+	execHttp.SetupLogRoutes(r, logHandler, cfg.JWTSecret)
+	execHttp.SetupEventRoutes(r, eventHandler, cfg.JWTSecret)
+	execHttp.SetupWebhookRoutes(r, webhookHandler, cfg.JWTSecret)
+	execHttp.SetupOutputRoutes(r, outputHandler, cfg.JWTSecret)
+	execHttp.SetupWorkerRoutes(r, workerHandler, cfg.JWTSecret)
+	execHttp.SetupUsageRoutes(r, usageHandler, cfg.JWTSecret)
 	objHttp.SetupObjectRoutes(r, objectHandler)
 	secretHttp.SetupSecretRoutes(r, secretHandler, cfg.JWTSecret)
 	// Start server
@@ -110,3 +216,14 @@ func main() {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// trustedProxies parses TRUSTED_PROXIES; none are trusted when it is empty
+func trustedProxies(spec string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(spec, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
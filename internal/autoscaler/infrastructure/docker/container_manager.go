@@ -85,8 +85,9 @@ func (m *DockerContainerManager) RunContainer(name string, replicas int) error {
 }
 
 func (m *DockerContainerManager) StopContainer(name string) error {
-	// Primero detener el contenedor
-	timeoutSeconds := int(10)
+	// Primero detener el contenedor, dando tiempo al worker para drenar sus
+	// ejecuciones (DRAIN_GRACE_PERIOD + STOP_GRACE_PERIOD)
+	timeoutSeconds := int(60)
 	if err := m.client.ContainerStop(context.Background(), name, container.StopOptions{Timeout: &timeoutSeconds}); err != nil {
 		return fmt.Errorf("failed to stop container %s: %v", name, err)
 	}
@@ -0,0 +1,6 @@
+package dto
+
+type StreamEventsRequest struct {
+	FunctionID  string `form:"function_id"`
+	ExecutionID string `form:"execution_id"`
+}
@@ -8,6 +8,14 @@ import (
 type CreateExecutionRequest struct {
 	FunctionID string `json:"function_id" binding:"required"`
 	Input      string `json:"input"`
+	Priority   string `json:"priority" binding:"omitempty,oneof=high normal batch"`
+	// Optional scheduling: an absolute time or a delay such as "15m"
+	RunAt *time.Time `json:"run_at"`
+	Delay string     `json:"delay"`
+	// Optional URL notified when the execution finishes, instead of the function's
+	CallbackURL string `json:"callback_url" binding:"omitempty,url"`
+	// Run the function even if a cached result exists
+	NoCache bool `json:"no_cache"`
 	//Input struct {
 	//	DirectInputs map[string]interface{} `json:"direct_inputs,omitempty"`
 	//	ObjectInputs map[string]string      `json:"object_inputs,omitempty"`
@@ -15,28 +23,96 @@ type CreateExecutionRequest struct {
 	//} `json:"input" validate:"required"`
 }
 
+type RerunExecutionRequest struct {
+	// Replaces the original input entirely
+	Input *string `json:"input"`
+	// Merged over the direct_inputs of the original input
+	DirectInputs map[string]interface{} `json:"direct_inputs"`
+	// Run the exact image digest the original execution ran with
+	PinImage bool   `json:"pin_image"`
+	Priority string `json:"priority" binding:"omitempty,oneof=high normal batch"`
+}
+
+type ListExecutionsRequest struct {
+	Status       string    `form:"status" binding:"omitempty,oneof=scheduled pending running completed failed"`
+	FunctionID   string    `form:"function_id"`
+	FailureClass string    `form:"failure_class" binding:"omitempty,oneof=timeout function_error infrastructure"`
+	CreatedFrom  time.Time `form:"created_from"`
+	CreatedTo    time.Time `form:"created_to"`
+	Order        string    `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor       string    `form:"cursor"`
+	Limit        int       `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type ListExecutionsResponse struct {
+	Executions []*ExecutionResponse `json:"executions"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
 type ExecutionResponse struct {
-	ID          string     `json:"id"`
-	FunctionID  string     `json:"function_id"`
-	Status      string     `json:"status"`
-	Input       string     `json:"input"`
-	Output      string     `json:"output,omitempty"`
-	Error       string     `json:"error,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	StartedAt   *time.Time `json:"started_at,omitempty"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ID                string                   `json:"id"`
+	FunctionID        string                   `json:"function_id"`
+	Status            string                   `json:"status"`
+	Priority          string                   `json:"priority,omitempty"`
+	Input             string                   `json:"input"`
+	Output            string                   `json:"output,omitempty"`
+	OutputRef         *entity.OutputRef        `json:"output_ref,omitempty"`
+	Error             string                   `json:"error,omitempty"`
+	FailureClass      string                   `json:"failure_class,omitempty"`
+	CallbackURL       string                   `json:"callback_url,omitempty"`
+	RerunOf           string                   `json:"rerun_of,omitempty"`
+	ParentExecutionID string                   `json:"parent_execution_id,omitempty"`
+	RootExecutionID   string                   `json:"root_execution_id,omitempty"`
+	CallDepth         int                      `json:"call_depth,omitempty"`
+	PinnedImage       string                   `json:"pinned_image,omitempty"`
+	ImageDigest       string                   `json:"image_digest,omitempty"`
+	SandboxProfile    string                   `json:"sandbox_profile,omitempty"`
+	CreatedAt         time.Time                `json:"created_at"`
+	RunAt             *time.Time               `json:"run_at,omitempty"`
+	StartedAt         *time.Time               `json:"started_at,omitempty"`
+	CompletedAt       *time.Time               `json:"completed_at,omitempty"`
+	Timeline          []*entity.TimelineEvent  `json:"timeline,omitempty"`
+	Timings           *entity.ExecutionTimings `json:"timings,omitempty"`
+	Usage             *entity.ResourceUsage    `json:"usage,omitempty"`
+	CacheHit          bool                     `json:"cache_hit,omitempty"`
+	CachedFrom        string                   `json:"cached_from,omitempty"`
+	Attempt           int                      `json:"attempt,omitempty"`
+	TraceID           string                   `json:"trace_id,omitempty"`
+	Progress          *int                     `json:"progress,omitempty"`
+	StatusMessage     string                   `json:"status_message,omitempty"`
 }
 
 func NewExecutionResponse(execution *entity.Execution) *ExecutionResponse {
 	return &ExecutionResponse{
-		ID:          execution.ID,
-		FunctionID:  execution.FunctionID,
-		Status:      string(execution.Status),
-		Input:       execution.Input,
-		Output:      execution.Output,
-		Error:       execution.Error,
-		CreatedAt:   execution.CreatedAt,
-		StartedAt:   execution.StartedAt,
-		CompletedAt: execution.CompletedAt,
+		ID:                execution.ID,
+		FunctionID:        execution.FunctionID,
+		Status:            string(execution.Status),
+		Priority:          string(execution.Priority),
+		Input:             execution.Input,
+		Output:            execution.Output,
+		OutputRef:         execution.OutputRef,
+		Error:             execution.Error,
+		FailureClass:      string(execution.FailureClass),
+		CallbackURL:       execution.CallbackURL,
+		RerunOf:           execution.RerunOf,
+		ParentExecutionID: execution.ParentExecutionID,
+		RootExecutionID:   execution.RootExecutionID,
+		CallDepth:         execution.CallDepth,
+		PinnedImage:       execution.PinnedImage,
+		ImageDigest:       execution.ImageDigest,
+		SandboxProfile:    execution.SandboxProfile,
+		CreatedAt:         execution.CreatedAt,
+		RunAt:             execution.RunAt,
+		StartedAt:         execution.StartedAt,
+		CompletedAt:       execution.CompletedAt,
+		Timeline:          execution.Timeline,
+		Timings:           execution.Timings(),
+		Usage:             execution.Usage,
+		CacheHit:          execution.CacheHit,
+		CachedFrom:        execution.CachedFrom,
+		Attempt:           execution.Attempt,
+		TraceID:           execution.TraceID,
+		Progress:          execution.Progress,
+		StatusMessage:     execution.StatusMessage,
 	}
 }
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"faas/internal/features/executions/domain/entity"
+	"time"
+)
+
+// ExecutionTreeNode is an execution of a call tree with the executions it
+// started, oldest first
+type ExecutionTreeNode struct {
+	ID           string               `json:"id"`
+	FunctionID   string               `json:"function_id"`
+	Status       string               `json:"status"`
+	FailureClass string               `json:"failure_class,omitempty"`
+	CallDepth    int                  `json:"call_depth"`
+	Progress     *int                 `json:"progress,omitempty"`
+	CreatedAt    time.Time            `json:"created_at"`
+	StartedAt    *time.Time           `json:"started_at,omitempty"`
+	CompletedAt  *time.Time           `json:"completed_at,omitempty"`
+	Children     []*ExecutionTreeNode `json:"children"`
+}
+
+func NewExecutionTreeNode(execution *entity.Execution) *ExecutionTreeNode {
+	return &ExecutionTreeNode{
+		ID:           execution.ID,
+		FunctionID:   execution.FunctionID,
+		Status:       string(execution.Status),
+		FailureClass: string(execution.FailureClass),
+		CallDepth:    execution.CallDepth,
+		Progress:     execution.Progress,
+		CreatedAt:    execution.CreatedAt,
+		StartedAt:    execution.StartedAt,
+		CompletedAt:  execution.CompletedAt,
+		Children:     []*ExecutionTreeNode{},
+	}
+}
+
+// ExecutionTreeResponse is the whole call tree an execution belongs to
+type ExecutionTreeResponse struct {
+	ExecutionID string             `json:"execution_id"`
+	Root        *ExecutionTreeNode `json:"root"`
+	Executions  int                `json:"executions"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "faas/internal/features/executions/domain/entity"
+
+type GetLogsRequest struct {
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+	Tail   int    `form:"tail" binding:"omitempty,min=1"`
+	Stream string `form:"stream" binding:"omitempty,oneof=stdout stderr"`
+}
+
+type LogsResponse struct {
+	ExecutionID string             `json:"execution_id"`
+	Total       int                `json:"total"`
+	Entries     []*entity.LogEntry `json:"entries"`
+}
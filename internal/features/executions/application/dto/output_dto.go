@@ -0,0 +1,9 @@
+package dto
+
+import "io"
+
+type OutputContent struct {
+	Reader io.ReadCloser
+	Size   int64
+	SHA256 string
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "faas/internal/features/executions/domain/entity"
+
+type RetentionStatusResponse struct {
+	Bucket      *entity.BucketStats `json:"bucket"`
+	DefaultTTL  string              `json:"default_ttl"`
+	Interval    string              `json:"interval"`
+	Archive     bool                `json:"archive"`
+	LastRun     *entity.PurgeStats  `json:"last_run,omitempty"`
+	TotalPurged int                 `json:"total_purged"`
+}
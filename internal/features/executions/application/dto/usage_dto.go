@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"faas/internal/features/executions/domain/entity"
+	"time"
+)
+
+type UsageRequest struct {
+	From       time.Time `form:"from"`
+	To         time.Time `form:"to"`
+	GroupBy    string    `form:"group_by" binding:"omitempty,oneof=user function"`
+	UserID     string    `form:"user_id"`
+	FunctionID string    `form:"function_id"`
+	Format     string    `form:"format" binding:"omitempty,oneof=json csv"`
+}
+
+type UsageResponse struct {
+	From    *time.Time  `json:"from,omitempty"`
+	To      *time.Time  `json:"to,omitempty"`
+	GroupBy string      `json:"group_by"`
+	Rows    []*UsageRow `json:"rows"`
+	Total   *UsageRow   `json:"total"`
+}
+
+// UsageRow adds up the usage of the executions of a user or a function
+type UsageRow struct {
+	UserID     string `json:"user_id,omitempty"`
+	FunctionID string `json:"function_id,omitempty"`
+	Executions int    `json:"executions"`
+	entity.ResourceUsage
+}
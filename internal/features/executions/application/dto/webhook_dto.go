@@ -0,0 +1,7 @@
+package dto
+
+import "faas/internal/features/executions/domain/entity"
+
+type DeliveriesResponse struct {
+	Deliveries []*entity.WebhookDelivery `json:"deliveries"`
+}
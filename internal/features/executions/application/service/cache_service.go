@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	objectRepo "faas/internal/features/function_objects/domain/repository"
+	functionEntity "faas/internal/features/functions/domain/entity"
+	"faas/internal/shared/infrastructure/config"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CacheService finds reusable results for functions that opted into caching.
+// A cache key covers the function, its image reference and cache generation,
+// the normalized input and the checksums of the objects the input references.
+// As an image tag can move to another image, an entry is only reused while it
+// was produced by the digest the latest run of the function resolved.
+type CacheService struct {
+	cacheRepo  repository.CacheRepository
+	objectRepo objectRepo.ObjectRepository
+	defaultTTL time.Duration
+}
+
+func NewCacheService(cacheRepo repository.CacheRepository, objectRepo objectRepo.ObjectRepository, config *config.Config) *CacheService {
+	return &CacheService{
+		cacheRepo:  cacheRepo,
+		objectRepo: objectRepo,
+		defaultTTL: config.CacheDefaultTTL,
+	}
+}
+
+// Key returns the cache key of the execution
+func (s *CacheService) Key(ctx context.Context, function *functionEntity.Function, execution *entity.Execution) (string, error) {
+	imageRef := function.ImageURL
+	if execution.PinnedImage != "" {
+		imageRef = execution.PinnedImage
+	}
+
+	input, objectRefs, err := normalizeInput(execution.Input)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "function:%s\nimage:%s\ngeneration:%d\ninput:%s\n", function.ID, imageRef, function.CacheGeneration, input)
+	if len(function.Command) > 0 {
+		fmt.Fprintf(h, "command:%q\n", function.Command)
+	}
+	for _, ref := range objectRefs {
+		checksum, err := s.objectChecksum(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "object:%s=%s\n", ref, checksum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Lookup returns the cached result for key, or nil if there is none or it
+// is older than the function's cache TTL
+func (s *CacheService) Lookup(ctx context.Context, function *functionEntity.Function, key string) (*entity.CacheEntry, error) {
+	entry, err := s.cacheRepo.Get(ctx, key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if entry.Expired(s.ttl(function), time.Now()) {
+		return nil, nil
+	}
+
+	latest, err := s.cacheRepo.LatestDigest(ctx, function.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != "" && entry.ImageDigest != latest {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func (s *CacheService) ttl(function *functionEntity.Function) time.Duration {
+	if ttl, err := time.ParseDuration(function.CacheTTL); err == nil && ttl > 0 {
+		return ttl
+	}
+	return s.defaultTTL
+}
+
+// objectChecksum identifies the content of an object reference such as
+// "function_id/name". Objects uploaded before checksums were recorded fall
+// back to their last update time.
+func (s *CacheService) objectChecksum(ctx context.Context, ref string) (string, error) {
+	functionID, name, found := strings.Cut(ref, "/")
+	if !found {
+		return "", fmt.Errorf("invalid object reference %q", ref)
+	}
+
+	object, err := s.objectRepo.Stat(ctx, functionID, name)
+	if err != nil {
+		return "", fmt.Errorf("object %s: %w", ref, err)
+	}
+	if object.SHA256 != "" {
+		return object.SHA256, nil
+	}
+	return "updated:" + object.UpdatedAt.UTC().Format(time.RFC3339Nano), nil
+}
+
+// normalizeInput re-encodes a JSON input with sorted keys and no whitespace,
+// and returns the sorted object references of its object_inputs. Inputs that
+// are not JSON are used as they are.
+func normalizeInput(input string) (string, []string, error) {
+	if strings.TrimSpace(input) == "" {
+		return "", nil, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	var parsed interface{}
+	if err := decoder.Decode(&parsed); err != nil {
+		return input, nil, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(parsed); err != nil {
+		return "", nil, err
+	}
+
+	var refs []string
+	if object, ok := parsed.(map[string]interface{}); ok {
+		if objectInputs, ok := object["object_inputs"].(map[string]interface{}); ok {
+			for _, value := range objectInputs {
+				if ref, ok := value.(string); ok {
+					refs = append(refs, ref)
+				}
+			}
+		}
+	}
+	sort.Strings(refs)
+
+	return strings.TrimSuffix(buf.String(), "\n"), refs, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		wantRefs []string
+	}{
+		{
+			name:  "keys are sorted",
+			input: `{"b": 1, "a": {"d": true, "c": null}}`,
+			want:  `{"a":{"c":null,"d":true},"b":1}`,
+		},
+		{
+			name:  "numbers keep their text",
+			input: `{"big": 12345678901234567890, "float": 1.50}`,
+			want:  `{"big":12345678901234567890,"float":1.50}`,
+		},
+		{
+			name:  "html is not escaped",
+			input: `{"html": "<b>&</b>"}`,
+			want:  `{"html":"<b>&</b>"}`,
+		},
+		{
+			name:     "object references are sorted",
+			input:    `{"object_inputs": {"z": "f1/b.txt", "a": "f1/a.txt", "n": 3}}`,
+			want:     `{"object_inputs":{"a":"f1/a.txt","n":3,"z":"f1/b.txt"}}`,
+			wantRefs: []string{"f1/a.txt", "f1/b.txt"},
+		},
+		{
+			name:  "not JSON is kept as is",
+			input: "plain text input",
+			want:  "plain text input",
+		},
+		{
+			name:  "blank",
+			input: "  ",
+			want:  "",
+		},
+		{
+			name:  "arrays keep their order",
+			input: `[3, 1, 2]`,
+			want:  `[3,1,2]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, refs, err := normalizeInput(tt.input)
+			if err != nil {
+				t.Fatalf("normalizeInput(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeInput(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if !reflect.DeepEqual(refs, tt.wantRefs) {
+				t.Errorf("normalizeInput(%q) refs = %v, want %v", tt.input, refs, tt.wantRefs)
+			}
+		})
+	}
+}
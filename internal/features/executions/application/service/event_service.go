@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	functionRepo "faas/internal/features/functions/domain/repository"
+	"faas/internal/shared/domain/errors"
+	"log"
+)
+
+type EventService struct {
+	executionRepo repository.ExecutionRepository
+	eventRepo     repository.ExecutionEventRepository
+	functionRepo  functionRepo.FunctionRepository
+}
+
+func NewEventService(repo repository.ExecutionRepository, eventRepo repository.ExecutionEventRepository, functionRepo functionRepo.FunctionRepository) *EventService {
+	return &EventService{
+		executionRepo: repo,
+		eventRepo:     eventRepo,
+		functionRepo:  functionRepo,
+	}
+}
+
+// StreamEvents delivers the events of the user's executions until ctx is done
+func (s *EventService) StreamEvents(ctx context.Context, req *dto.StreamEventsRequest, userID string) (<-chan *entity.ExecutionEvent, error) {
+	if req.FunctionID != "" {
+		function, err := s.functionRepo.GetByID(ctx, req.FunctionID)
+		if err != nil {
+			return nil, errors.NewAppError("function_not_found", "Function not found")
+		}
+		if function.UserID != userID {
+			return nil, errors.NewAppError("unauthorized", "Not authorized to view this function")
+		}
+	}
+
+	if req.ExecutionID != "" {
+		execution, err := s.executionRepo.GetByID(ctx, req.ExecutionID)
+		if err != nil {
+			return nil, errors.NewAppError("execution_not_found", "Execution not found")
+		}
+		if execution.UserID != userID {
+			return nil, errors.NewAppError("unauthorized", "Not authorized to view this execution")
+		}
+	}
+
+	events, err := s.eventRepo.Subscribe(ctx, userID, req.FunctionID, req.ExecutionID)
+	if err != nil {
+		return nil, errors.NewAppError("stream_events_failed", err.Error())
+	}
+	return events, nil
+}
+
+// publishStatus announces the current status of the execution. Events are
+// best effort: a failed publish never fails the transition itself.
+func publishStatus(ctx context.Context, eventRepo repository.ExecutionEventRepository, execution *entity.Execution) {
+	if err := eventRepo.Publish(ctx, entity.NewStatusEvent(execution)); err != nil {
+		log.Printf("Error publishing status event of execution %s: %v", execution.ID, err)
+	}
+}
@@ -2,45 +2,163 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"faas/internal/features/executions/application/dto"
 	"faas/internal/features/executions/domain/entity"
 	"faas/internal/features/executions/domain/repository"
 	functionRepo "faas/internal/features/functions/domain/repository"
 	"faas/internal/shared/domain/errors"
+	"faas/internal/shared/infrastructure/callback"
 	"faas/internal/shared/infrastructure/config"
 	"fmt"
-	"strconv"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const defaultPageSize = 50
+
 type ExecutionService struct {
 	executionRepo       repository.ExecutionRepository
 	executionStreamRepo repository.ExecutionStreamRepository
+	scheduleRepo        repository.ScheduleRepository
+	eventRepo           repository.ExecutionEventRepository
 	functionRepo        functionRepo.FunctionRepository
+	quotaService        *QuotaService
+	cacheService        *CacheService
 	config              *config.Config
+	maxCallDepth        int
 }
 
-func NewExecutionService(repo repository.ExecutionRepository, streamRepo repository.ExecutionStreamRepository, functionRepo functionRepo.FunctionRepository, config *config.Config) *ExecutionService {
+func NewExecutionService(repo repository.ExecutionRepository, streamRepo repository.ExecutionStreamRepository, scheduleRepo repository.ScheduleRepository, eventRepo repository.ExecutionEventRepository, functionRepo functionRepo.FunctionRepository, quotaService *QuotaService, cacheService *CacheService, config *config.Config) *ExecutionService {
 	return &ExecutionService{
 		executionRepo:       repo,
 		executionStreamRepo: streamRepo,
+		scheduleRepo:        scheduleRepo,
+		eventRepo:           eventRepo,
 		functionRepo:        functionRepo,
+		quotaService:        quotaService,
+		cacheService:        cacheService,
 		config:              config,
+		maxCallDepth:        config.MaxCallDepth,
 	}
 }
 
-func (s *ExecutionService) CreateExecution(ctx context.Context, req *dto.CreateExecutionRequest, userID string) (*dto.ExecutionResponse, error) {
-	// Check execution limit
-	count, err := s.executionRepo.GetActiveExecutionCount(ctx, userID)
+// CreateExecution starts an execution for the user. parentID is set when a
+// running execution invokes the function with its scoped token; the new
+// execution then joins the parent's call tree and trace.
+func (s *ExecutionService) CreateExecution(ctx context.Context, req *dto.CreateExecutionRequest, userID string, parentID string) (*dto.ExecutionResponse, error) {
+	if parentID == "" {
+		return s.createExecution(ctx, req, userID, nil)
+	}
+
+	parent, err := s.executionRepo.GetByID(ctx, parentID)
 	if err != nil {
-		return nil, err
+		return nil, errors.NewAppError("execution_not_found", "Parent execution not found")
+	}
+	if parent.UserID != userID {
+		return nil, errors.NewAppError("unauthorized", "Not authorized to invoke functions from this execution")
+	}
+	if parent.IsTerminal() {
+		return nil, errors.NewAppError("invalid_parent", "The parent execution has already finished")
+	}
+
+	depth := parent.CallDepth + 1
+	if depth > s.maxCallDepth {
+		return nil, errors.NewAppError("call_depth_exceeded", fmt.Sprintf("Maximum call depth of %d exceeded", s.maxCallDepth))
+	}
+
+	return s.createExecution(ctx, req, userID, func(execution *entity.Execution) {
+		execution.ParentExecutionID = parent.ID
+		execution.RootExecutionID = parent.RootID()
+		execution.CallDepth = depth
+		execution.TraceID = parent.TraceIDOrDefault()
+	})
+}
+
+// RerunExecution starts a new execution of the same function with the
+// original input, optionally overridden, linked to the original by rerun_of
+func (s *ExecutionService) RerunExecution(ctx context.Context, id string, req *dto.RerunExecutionRequest, userID string) (*dto.ExecutionResponse, error) {
+	original, err := s.executionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("execution_not_found", "Execution not found")
+	}
+
+	if original.UserID != userID {
+		return nil, errors.NewAppError("unauthorized", "Not authorized to rerun this execution")
+	}
+
+	input := original.Input
+	if req.Input != nil {
+		input = *req.Input
+	}
+	if len(req.DirectInputs) > 0 {
+		input, err = mergeDirectInputs(input, req.DirectInputs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var pinnedImage string
+	if req.PinImage {
+		pinnedImage = original.ImageDigest
+		if pinnedImage == "" {
+			pinnedImage = original.PinnedImage
+		}
+		if pinnedImage == "" {
+			return nil, errors.NewAppError("invalid_rerun", "The original execution has no recorded image digest to pin")
+		}
+	}
+
+	createReq := &dto.CreateExecutionRequest{
+		FunctionID:  original.FunctionID,
+		Input:       input,
+		Priority:    req.Priority,
+		CallbackURL: original.CallbackURL,
+	}
+	if createReq.Priority == "" {
+		createReq.Priority = string(original.Priority)
 	}
 
-	maxExecutions, _ := strconv.Atoi(s.config.MaxConcurrentExecutions)
-	if count >= maxExecutions {
-		return nil, fmt.Errorf("execution limit exceeded: maximum %d concurrent executions", maxExecutions)
+	return s.createExecution(ctx, createReq, userID, func(execution *entity.Execution) {
+		execution.RerunOf = original.ID
+		execution.PinnedImage = pinnedImage
+	})
+}
+
+// mergeDirectInputs overrides keys of the direct_inputs section of a JSON input
+func mergeDirectInputs(input string, overrides map[string]interface{}) (string, error) {
+	parsed := map[string]interface{}{}
+	if input != "" {
+		if err := json.Unmarshal([]byte(input), &parsed); err != nil {
+			return "", errors.NewAppError("invalid_input", "The original input is not a JSON object")
+		}
+	}
+
+	directInputs, _ := parsed["direct_inputs"].(map[string]interface{})
+	if directInputs == nil {
+		directInputs = map[string]interface{}{}
+	}
+	for key, value := range overrides {
+		directInputs[key] = value
+	}
+	parsed["direct_inputs"] = directInputs
+
+	merged, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+// createExecution saves and queues (or schedules) a new execution. prepare,
+// when set, fills in extra fields before the execution is saved.
+func (s *ExecutionService) createExecution(ctx context.Context, req *dto.CreateExecutionRequest, userID string, prepare func(execution *entity.Execution)) (*dto.ExecutionResponse, error) {
+	runAt, err := resolveRunAt(req)
+	if err != nil {
+		return nil, err
 	}
 
 	//Get function and validate if this function has the same userID
@@ -53,28 +171,139 @@ func (s *ExecutionService) CreateExecution(ctx context.Context, req *dto.CreateE
 		return nil, errors.NewAppError("unauthorized", "Not authorized to execute this function")
 	}
 
+	// Per-execution priority wins over the function default
+	priority := entity.ExecutionPriority(req.Priority)
+	if priority == "" {
+		priority = entity.ExecutionPriority(function.Priority)
+	}
+	if !priority.IsValid() {
+		priority = entity.PriorityNormal
+	}
+
+	// The function's callback applies unless the execution has its own. It is
+	// copied so the callback is queued together with the final status.
+	callbackURL := req.CallbackURL
+	if callbackURL != "" {
+		if err := callback.ValidateURL(callbackURL); err != nil {
+			return nil, errors.NewAppError("invalid_callback_url", err.Error())
+		}
+	} else {
+		callbackURL = function.CallbackURL
+	}
+
 	// Create execution
 	execution := &entity.Execution{
-		ID:         uuid.New().String(),
-		FunctionID: req.FunctionID,
-		UserID:     userID,
-		Status:     entity.StatusPending,
-		Input:      req.Input,
-		CreatedAt:  time.Now(),
+		ID:          uuid.New().String(),
+		FunctionID:  req.FunctionID,
+		UserID:      userID,
+		Status:      entity.StatusPending,
+		Priority:    priority,
+		Input:       req.Input,
+		CallbackURL: callbackURL,
+		TraceID:     strings.ReplaceAll(uuid.New().String(), "-", ""),
+		CreatedAt:   time.Now(),
 	}
+	if prepare != nil {
+		prepare(execution)
+	}
+
+	// Scheduled executions and reruns always run; their result still
+	// refreshes the cache
+	scheduled := runAt != nil && runAt.After(execution.CreatedAt)
+	if function.CacheEnabled {
+		key, err := s.cacheService.Key(ctx, function, execution)
+		if err != nil {
+			log.Printf("Not caching execution %s: %v", execution.ID, err)
+		}
+		execution.CacheKey = key
+
+		if key != "" && !scheduled && execution.RerunOf == "" && !req.NoCache {
+			entry, err := s.cacheService.Lookup(ctx, function, key)
+			if err != nil {
+				log.Printf("Error looking up cached result of execution %s: %v", execution.ID, err)
+			}
+			if entry != nil {
+				return s.completeFromCache(ctx, execution, entry)
+			}
+		}
+	}
+
+	// Executions due in the future wait in the schedule for the dispatcher
+	if scheduled {
+		execution.Status = entity.StatusScheduled
+		execution.RunAt = runAt
 
+		if err := s.executionRepo.Save(ctx, execution); err != nil {
+			return nil, err
+		}
+		if err := s.scheduleRepo.Schedule(ctx, execution); err != nil {
+			return nil, err
+		}
+		publishStatus(ctx, s.eventRepo, execution)
+		return dto.NewExecutionResponse(execution), nil
+	}
+
+	// Take the concurrency slots; the worker releases them once it finishes
+	if err := s.quotaService.Reserve(ctx, execution); err != nil {
+		return nil, err
+	}
+
+	execution.Record(entity.PhaseQueued, "")
 	if err := s.executionRepo.Save(ctx, execution); err != nil {
+		s.quotaService.Release(ctx, execution)
 		return nil, err
 	}
 
 	if err := s.executionStreamRepo.PublishPending(execution); err != nil {
+		s.quotaService.Release(ctx, execution)
+		return nil, err
+	}
+	publishStatus(ctx, s.eventRepo, execution)
+
+	return dto.NewExecutionResponse(execution), nil
+}
+
+// completeFromCache finishes the execution right away with a cached result
+func (s *ExecutionService) completeFromCache(ctx context.Context, execution *entity.Execution, entry *entity.CacheEntry) (*dto.ExecutionResponse, error) {
+	now := time.Now()
+	execution.Status = entity.StatusCompleted
+	execution.Output = entry.Output
+	execution.ImageDigest = entry.ImageDigest
+	execution.CacheHit = true
+	execution.CachedFrom = entry.ExecutionID
+	execution.StartedAt = &now
+	execution.CompletedAt = &now
+	execution.Record(entity.PhaseCacheHit, entry.ExecutionID)
+
+	if err := s.executionRepo.Save(ctx, execution); err != nil {
 		return nil, err
 	}
+	publishStatus(ctx, s.eventRepo, execution)
 
 	return dto.NewExecutionResponse(execution), nil
 }
 
-func (s *ExecutionService) GetExecution(ctx context.Context, id string, userID string) (*dto.ExecutionResponse, error) {
+// resolveRunAt returns when the execution should run, or nil to run it now
+func resolveRunAt(req *dto.CreateExecutionRequest) (*time.Time, error) {
+	if req.RunAt != nil && req.Delay != "" {
+		return nil, errors.NewAppError("invalid_schedule", "Only one of run_at and delay can be set")
+	}
+
+	if req.Delay != "" {
+		delay, err := time.ParseDuration(req.Delay)
+		if err != nil || delay < 0 {
+			return nil, errors.NewAppError("invalid_schedule", "Invalid delay: "+req.Delay)
+		}
+		runAt := time.Now().Add(delay)
+		return &runAt, nil
+	}
+
+	return req.RunAt, nil
+}
+
+// GetExecution returns an execution of the user. With the scoped token of a
+// running execution (parentID) only that execution and its children are visible.
+func (s *ExecutionService) GetExecution(ctx context.Context, id string, userID string, parentID string) (*dto.ExecutionResponse, error) {
 	execution, err := s.executionRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, errors.NewAppError("execution_not_found", "Execution not found")
@@ -83,20 +312,99 @@ func (s *ExecutionService) GetExecution(ctx context.Context, id string, userID s
 	if execution.UserID != userID {
 		return nil, errors.NewAppError("unauthorized", "Not authorized to view this execution")
 	}
+	if parentID != "" && execution.ID != parentID && execution.ParentExecutionID != parentID {
+		return nil, errors.NewAppError("unauthorized", "Not authorized to view this execution")
+	}
 
 	return dto.NewExecutionResponse(execution), nil
 }
 
-func (s *ExecutionService) ListUserExecutions(ctx context.Context, userID string) ([]*dto.ExecutionResponse, error) {
-	executions, err := s.executionRepo.ListByUserID(ctx, userID)
+// GetExecutionTree returns the whole call tree the execution belongs to,
+// starting from the execution that was invoked directly
+func (s *ExecutionService) GetExecutionTree(ctx context.Context, id string, userID string) (*dto.ExecutionTreeResponse, error) {
+	execution, err := s.executionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("execution_not_found", "Execution not found")
+	}
+
+	if execution.UserID != userID {
+		return nil, errors.NewAppError("unauthorized", "Not authorized to view this execution")
+	}
+
+	root := execution
+	if execution.RootID() != execution.ID {
+		root, err = s.executionRepo.GetByID(ctx, execution.RootID())
+		if err != nil {
+			// The root was purged; show what is left below the execution
+			root = execution
+		}
+	}
+
+	tree := &dto.ExecutionTreeResponse{ExecutionID: execution.ID}
+	tree.Root, err = s.executionTreeNode(ctx, root, &tree.Executions)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// executionTreeNode builds the node of execution and its descendants,
+// counting the executions visited
+func (s *ExecutionService) executionTreeNode(ctx context.Context, execution *entity.Execution, count *int) (*dto.ExecutionTreeNode, error) {
+	node := dto.NewExecutionTreeNode(execution)
+	*count++
+
+	// Depths are capped when executions are created; this only guards the
+	// walk against corrupt links
+	if execution.CallDepth > s.maxCallDepth {
+		return node, nil
+	}
+
+	children, err := s.executionRepo.ListChildren(ctx, execution.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		childNode, err := s.executionTreeNode(ctx, child, count)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, childNode)
+	}
+	return node, nil
+}
+
+func (s *ExecutionService) ListUserExecutions(ctx context.Context, req *dto.ListExecutionsRequest, userID string) (*dto.ListExecutionsResponse, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	page, err := s.executionRepo.List(ctx, &entity.ExecutionQuery{
+		UserID:       userID,
+		FunctionID:   req.FunctionID,
+		Status:       entity.ExecutionStatus(req.Status),
+		FailureClass: entity.FailureClass(req.FailureClass),
+		CreatedFrom:  req.CreatedFrom,
+		CreatedTo:    req.CreatedTo,
+		Ascending:    req.Order == "asc",
+		Cursor:       req.Cursor,
+		Limit:        limit,
+	})
 	if err != nil {
+		if errors.Code(err) != "" {
+			return nil, err
+		}
 		return nil, errors.NewAppError("list_executions_failed", err.Error())
 	}
 
-	responses := make([]*dto.ExecutionResponse, len(executions))
-	for i, execution := range executions {
+	responses := make([]*dto.ExecutionResponse, len(page.Executions))
+	for i, execution := range page.Executions {
 		responses[i] = dto.NewExecutionResponse(execution)
 	}
 
-	return responses, nil
+	return &dto.ListExecutionsResponse{
+		Executions: responses,
+		NextCursor: page.NextCursor,
+	}, nil
 }
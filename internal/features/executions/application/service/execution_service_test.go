@@ -0,0 +1,75 @@
+package service
+
+import (
+	"encoding/json"
+	"faas/internal/shared/domain/errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergeDirectInputs(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		overrides map[string]interface{}
+		want      string
+		wantCode  string
+	}{
+		{
+			name:      "overrides and adds keys",
+			input:     `{"direct_inputs": {"value": 1, "unit": "kg"}, "object_inputs": {"file": "f1/a.txt"}}`,
+			overrides: map[string]interface{}{"value": 10.0, "scale": 2.0},
+			want:      `{"direct_inputs": {"value": 10, "unit": "kg", "scale": 2}, "object_inputs": {"file": "f1/a.txt"}}`,
+		},
+		{
+			name:      "input without direct inputs",
+			input:     `{"object_inputs": {"file": "f1/a.txt"}}`,
+			overrides: map[string]interface{}{"value": 10.0},
+			want:      `{"direct_inputs": {"value": 10}, "object_inputs": {"file": "f1/a.txt"}}`,
+		},
+		{
+			name:      "empty input",
+			input:     "",
+			overrides: map[string]interface{}{"value": "x"},
+			want:      `{"direct_inputs": {"value": "x"}}`,
+		},
+		{
+			name:      "direct inputs that are not an object are replaced",
+			input:     `{"direct_inputs": [1, 2]}`,
+			overrides: map[string]interface{}{"value": true},
+			want:      `{"direct_inputs": {"value": true}}`,
+		},
+		{
+			name:      "input that is not an object",
+			input:     "plain text",
+			overrides: map[string]interface{}{"value": 1.0},
+			wantCode:  "invalid_input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeDirectInputs(tt.input, tt.overrides)
+			if tt.wantCode != "" {
+				if code := errors.Code(err); code != tt.wantCode {
+					t.Fatalf("mergeDirectInputs() error code = %q, want %q", code, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeDirectInputs() error = %v", err)
+			}
+
+			var gotValue, wantValue interface{}
+			if err := json.Unmarshal([]byte(got), &gotValue); err != nil {
+				t.Fatalf("mergeDirectInputs() = %q is not JSON: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("mergeDirectInputs() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	appErrors "faas/internal/shared/domain/errors"
+	"time"
+)
+
+// logIdleCheckInterval is how long a followed execution stays quiet before
+// its status is checked, for executions whose end marker was never published
+const logIdleCheckInterval = 5 * time.Second
+
+type LogService struct {
+	executionRepo repository.ExecutionRepository
+	logRepo       repository.ExecutionLogRepository
+}
+
+func NewLogService(repo repository.ExecutionRepository, logRepo repository.ExecutionLogRepository) *LogService {
+	return &LogService{
+		executionRepo: repo,
+		logRepo:       logRepo,
+	}
+}
+
+func (s *LogService) GetLogs(ctx context.Context, id string, userID string, req *dto.GetLogsRequest) (*dto.LogsResponse, error) {
+	if _, err := s.authorize(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.logRepo.List(ctx, id)
+	if err != nil {
+		return nil, appErrors.NewAppError("get_logs_failed", err.Error())
+	}
+	total := len(entries)
+
+	if req.Offset > 0 {
+		if req.Offset > len(entries) {
+			req.Offset = len(entries)
+		}
+		entries = entries[req.Offset:]
+	}
+
+	if req.Stream != "" {
+		filtered := make([]*entity.LogEntry, 0, len(entries))
+		for _, entry := range entries {
+			if entry.Stream == req.Stream {
+				filtered = append(filtered, entry)
+			}
+		}
+		entries = filtered
+	}
+
+	if req.Tail > 0 && req.Tail < len(entries) {
+		entries = entries[len(entries)-req.Tail:]
+	}
+
+	return &dto.LogsResponse{
+		ExecutionID: id,
+		Total:       total,
+		Entries:     entries,
+	}, nil
+}
+
+// StreamLogs delivers the lines logged so far and then follows the execution
+// until it finishes. The channel is closed at the end of the logs, or once the
+// execution is finished and no line came for logIdleCheckInterval.
+func (s *LogService) StreamLogs(ctx context.Context, id string, userID string) (<-chan *entity.LogEntry, error) {
+	execution, err := s.authorize(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Finished executions have nothing left to follow
+	if execution.IsTerminal() {
+		entries, err := s.logRepo.List(ctx, id)
+		if err != nil {
+			return nil, appErrors.NewAppError("get_logs_failed", err.Error())
+		}
+		ch := make(chan *entity.LogEntry, len(entries))
+		for _, entry := range entries {
+			ch <- entry
+		}
+		close(ch)
+		return ch, nil
+	}
+
+	followCtx, cancel := context.WithCancel(ctx)
+	source, err := s.logRepo.Follow(followCtx, id)
+	if err != nil {
+		cancel()
+		return nil, appErrors.NewAppError("get_logs_failed", err.Error())
+	}
+
+	entries := make(chan *entity.LogEntry)
+	go func() {
+		defer close(entries)
+		defer cancel()
+
+		ticker := time.NewTicker(logIdleCheckInterval)
+		defer ticker.Stop()
+
+		idle := false
+		for {
+			select {
+			case entry, ok := <-source:
+				if !ok {
+					return
+				}
+				idle = false
+				select {
+				case entries <- entry:
+				case <-ctx.Done():
+					return
+				}
+			case <-ticker.C:
+				if idle && s.finished(ctx, id) {
+					return
+				}
+				idle = true
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return entries, nil
+}
+
+// finished reports whether the execution ended or no longer exists
+func (s *LogService) finished(ctx context.Context, id string) bool {
+	execution, err := s.executionRepo.GetByID(ctx, id)
+	if err != nil {
+		return errors.Is(err, entity.ErrExecutionNotFound)
+	}
+	return execution.IsTerminal()
+}
+
+func (s *LogService) authorize(ctx context.Context, id string, userID string) (*entity.Execution, error) {
+	execution, err := s.executionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, appErrors.NewAppError("execution_not_found", "Execution not found")
+	}
+
+	if execution.UserID != userID {
+		return nil, appErrors.NewAppError("unauthorized", "Not authorized to view this execution")
+	}
+
+	return execution, nil
+}
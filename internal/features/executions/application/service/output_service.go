@@ -0,0 +1,52 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/domain/repository"
+	"faas/internal/shared/domain/errors"
+	"io"
+)
+
+type OutputService struct {
+	executionRepo repository.ExecutionRepository
+	outputRepo    repository.OutputRepository
+}
+
+func NewOutputService(repo repository.ExecutionRepository, outputRepo repository.OutputRepository) *OutputService {
+	return &OutputService{
+		executionRepo: repo,
+		outputRepo:    outputRepo,
+	}
+}
+
+// GetOutput opens the execution output, wherever it is stored. The caller
+// must close the returned content.
+func (s *OutputService) GetOutput(ctx context.Context, id string, userID string) (*dto.OutputContent, error) {
+	execution, err := s.executionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("execution_not_found", "Execution not found")
+	}
+
+	if execution.UserID != userID {
+		return nil, errors.NewAppError("unauthorized", "Not authorized to view this execution")
+	}
+
+	if execution.OutputRef == nil {
+		return &dto.OutputContent{
+			Reader: io.NopCloser(bytes.NewReader([]byte(execution.Output))),
+			Size:   int64(len(execution.Output)),
+		}, nil
+	}
+
+	reader, err := s.outputRepo.Get(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("output_not_found", "Output not found")
+	}
+	return &dto.OutputContent{
+		Reader: reader,
+		Size:   execution.OutputRef.Size,
+		SHA256: execution.OutputRef.SHA256,
+	}, nil
+}
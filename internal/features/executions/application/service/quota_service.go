@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	functionRepo "faas/internal/features/functions/domain/repository"
+	userRepo "faas/internal/features/users/domain/repository"
+	appErrors "faas/internal/shared/domain/errors"
+	"faas/internal/shared/infrastructure/config"
+	"fmt"
+	"log"
+	"time"
+)
+
+// QuotaRetryAfter is how long clients are told to wait when a quota is full
+const QuotaRetryAfter = 5 * time.Second
+
+// Slots are reserved before their execution is saved, so younger ones are
+// never reconciled
+const quotaReconcileGrace = time.Minute
+
+// QuotaService reserves concurrency slots for executions, per user (the user
+// limit or MAX_CONCURRENT_EXECUTIONS) and per function (when it sets one).
+// Slots are released by the worker once the execution finishes; those a
+// release missed are dropped by a periodic reconciliation.
+type QuotaService struct {
+	quotaRepo         repository.QuotaRepository
+	executionRepo     repository.ExecutionRepository
+	userRepo          userRepo.UserRepository
+	functionRepo      functionRepo.FunctionRepository
+	defaultLimit      int
+	reconcileInterval time.Duration
+}
+
+func NewQuotaService(quotaRepo repository.QuotaRepository, executionRepo repository.ExecutionRepository, userRepo userRepo.UserRepository, functionRepo functionRepo.FunctionRepository, config *config.Config) *QuotaService {
+	return &QuotaService{
+		quotaRepo:         quotaRepo,
+		executionRepo:     executionRepo,
+		userRepo:          userRepo,
+		functionRepo:      functionRepo,
+		defaultLimit:      config.MaxConcurrentExecutions,
+		reconcileInterval: config.QuotaReconcileInterval,
+	}
+}
+
+// Reserve takes the slots the execution needs, or none of them
+func (s *QuotaService) Reserve(ctx context.Context, execution *entity.Execution) error {
+	userLimit := s.defaultLimit
+	if user, err := s.userRepo.GetByID(ctx, execution.UserID); err == nil && user.MaxConcurrentExecutions > 0 {
+		userLimit = user.MaxConcurrentExecutions
+	}
+
+	ok, err := s.quotaRepo.Reserve(ctx, entity.UserQuotaKey(execution.UserID), execution.ID, userLimit)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return quotaExceeded("user", userLimit)
+	}
+
+	function, err := s.functionRepo.GetByID(ctx, execution.FunctionID)
+	if err != nil || function.MaxConcurrentExecutions <= 0 {
+		return nil
+	}
+
+	ok, err = s.quotaRepo.Reserve(ctx, entity.FunctionQuotaKey(execution.FunctionID), execution.ID, function.MaxConcurrentExecutions)
+	if err != nil || !ok {
+		s.Release(ctx, execution)
+		if err != nil {
+			return err
+		}
+		return quotaExceeded("function", function.MaxConcurrentExecutions)
+	}
+
+	return nil
+}
+
+// Release frees every slot held by the execution
+func (s *QuotaService) Release(ctx context.Context, execution *entity.Execution) {
+	for _, key := range execution.QuotaKeys() {
+		if err := s.quotaRepo.Release(ctx, key, execution.ID); err != nil {
+			log.Printf("Error releasing quota %s of execution %s: %v", key, execution.ID, err)
+		}
+	}
+}
+
+func (s *QuotaService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reconcile(ctx); err != nil {
+				log.Printf("Error reconciling quotas: %v", err)
+			}
+		}
+	}
+}
+
+// reconcile drops the slots of executions that finished or no longer exist,
+// which a lost worker or a failed release left behind
+func (s *QuotaService) reconcile(ctx context.Context) error {
+	slots, err := s.quotaRepo.Slots(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-quotaReconcileGrace)
+	released := 0
+	for _, slot := range slots {
+		if slot.ReservedAt.After(cutoff) {
+			continue
+		}
+
+		execution, err := s.executionRepo.GetByID(ctx, slot.ExecutionID)
+		switch {
+		case errors.Is(err, entity.ErrExecutionNotFound):
+		case err != nil:
+			log.Printf("Error checking quota slot of execution %s: %v", slot.ExecutionID, err)
+			continue
+		case !execution.IsTerminal():
+			continue
+		}
+
+		if err := s.quotaRepo.Release(ctx, slot.Key, slot.ExecutionID); err != nil {
+			log.Printf("Error releasing quota %s of execution %s: %v", slot.Key, slot.ExecutionID, err)
+			continue
+		}
+		released++
+	}
+
+	if released > 0 {
+		log.Printf("Released %d leftover quota slots", released)
+	}
+	return nil
+}
+
+func quotaExceeded(scope string, limit int) error {
+	return appErrors.NewAppError("quota_exceeded", fmt.Sprintf("Concurrent execution limit reached: maximum %d for this %s", limit, scope))
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	functionRepo "faas/internal/features/functions/domain/repository"
+	"faas/internal/shared/domain/errors"
+	"faas/internal/shared/infrastructure/config"
+	"log"
+	"sync"
+	"time"
+)
+
+// Executions archived per object
+const archiveBatchSize = 1000
+
+// RetentionService purges finished executions once their TTL has passed,
+// optionally archiving them to object storage first
+type RetentionService struct {
+	executionRepo repository.ExecutionRepository
+	archiveRepo   repository.ExecutionArchiveRepository
+	outputRepo    repository.OutputRepository
+	logRepo       repository.ExecutionLogRepository
+	functionRepo  functionRepo.FunctionRepository
+	defaultTTL    time.Duration
+	interval      time.Duration
+	archive       bool
+
+	mu          sync.Mutex
+	running     bool
+	lastRun     *entity.PurgeStats
+	totalPurged int
+}
+
+func NewRetentionService(repo repository.ExecutionRepository, archiveRepo repository.ExecutionArchiveRepository, outputRepo repository.OutputRepository, logRepo repository.ExecutionLogRepository, functionRepo functionRepo.FunctionRepository, config *config.Config) *RetentionService {
+	return &RetentionService{
+		executionRepo: repo,
+		archiveRepo:   archiveRepo,
+		outputRepo:    outputRepo,
+		logRepo:       logRepo,
+		functionRepo:  functionRepo,
+		// Purging is opt-in, executions are kept forever unless a TTL is set
+		defaultTTL: config.RetentionTTL,
+		interval:   config.RetentionInterval,
+		archive:    config.RetentionArchive,
+	}
+}
+
+func (s *RetentionService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := s.Purge(ctx)
+			if err != nil {
+				log.Printf("Error purging executions: %v", err)
+				continue
+			}
+			log.Printf("Retention run: scanned=%d purged=%d archived=%d errors=%d",
+				stats.Scanned, stats.Purged, stats.Archived, stats.Errors)
+		}
+	}
+}
+
+// Purge deletes every finished execution older than its retention TTL
+func (s *RetentionService) Purge(ctx context.Context) (*entity.PurgeStats, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, errors.NewAppError("purge_in_progress", "A retention run is already in progress")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	stats := &entity.PurgeStats{StartedAt: time.Now()}
+	ttls := make(map[string]time.Duration)
+	var batch []*entity.Execution
+
+	err := s.executionRepo.Walk(ctx, func(execution *entity.Execution) error {
+		stats.Scanned++
+		if !execution.IsTerminal() {
+			return nil
+		}
+
+		ttl, ok := ttls[execution.FunctionID]
+		if !ok {
+			ttl = s.ttlFor(ctx, execution.FunctionID)
+			ttls[execution.FunctionID] = ttl
+		}
+		if ttl == 0 {
+			return nil
+		}
+
+		finishedAt := execution.CreatedAt
+		if execution.CompletedAt != nil {
+			finishedAt = *execution.CompletedAt
+		}
+		if time.Since(finishedAt) < ttl {
+			return nil
+		}
+
+		batch = append(batch, execution)
+		if len(batch) >= archiveBatchSize {
+			s.purgeBatch(ctx, batch, stats)
+			batch = nil
+		}
+		return nil
+	})
+	if len(batch) > 0 {
+		s.purgeBatch(ctx, batch, stats)
+	}
+	stats.DurationMs = time.Since(stats.StartedAt).Milliseconds()
+
+	s.mu.Lock()
+	s.lastRun = stats
+	s.totalPurged += stats.Purged
+	s.mu.Unlock()
+
+	return stats, err
+}
+
+func (s *RetentionService) purgeBatch(ctx context.Context, batch []*entity.Execution, stats *entity.PurgeStats) {
+	// Never delete what could not be archived
+	if s.archive {
+		name, err := s.archiveRepo.Archive(ctx, batch)
+		if err != nil {
+			log.Printf("Error archiving %d executions: %v", len(batch), err)
+			stats.Errors += len(batch)
+			return
+		}
+		stats.Archived += len(batch)
+		stats.ArchiveObjects = append(stats.ArchiveObjects, name)
+	}
+
+	for _, execution := range batch {
+		// Offloaded outputs are not archived, they go with the execution
+		if execution.OutputRef != nil {
+			if err := s.outputRepo.Delete(ctx, execution.ID); err != nil {
+				log.Printf("Error deleting output of execution %s: %v", execution.ID, err)
+			}
+		}
+
+		if err := s.executionRepo.Delete(ctx, execution.ID); err != nil {
+			log.Printf("Error deleting execution %s: %v", execution.ID, err)
+			stats.Errors++
+			continue
+		}
+		stats.Purged++
+
+		// Logs are not archived either, and would outlive the execution
+		if err := s.logRepo.Delete(ctx, execution.ID); err != nil {
+			log.Printf("Error deleting logs of execution %s: %v", execution.ID, err)
+		}
+	}
+}
+
+// ttlFor returns the function retention TTL, falling back to the global one
+func (s *RetentionService) ttlFor(ctx context.Context, functionID string) time.Duration {
+	function, err := s.functionRepo.GetByID(ctx, functionID)
+	if err != nil || function.RetentionTTL == "" {
+		return s.defaultTTL
+	}
+
+	ttl, err := time.ParseDuration(function.RetentionTTL)
+	if err != nil || ttl <= 0 {
+		return s.defaultTTL
+	}
+	return ttl
+}
+
+func (s *RetentionService) GetStatus(ctx context.Context) (*dto.RetentionStatusResponse, error) {
+	bucket, err := s.executionRepo.Stats(ctx)
+	if err != nil {
+		return nil, errors.NewAppError("bucket_stats_failed", err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &dto.RetentionStatusResponse{
+		Bucket:      bucket,
+		DefaultTTL:  s.defaultTTL.String(),
+		Interval:    s.interval.String(),
+		Archive:     s.archive,
+		LastRun:     s.lastRun,
+		TotalPurged: s.totalPurged,
+	}, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	appErrors "faas/internal/shared/domain/errors"
+	"faas/internal/shared/infrastructure/config"
+	"log"
+	"time"
+)
+
+// scheduleClaimTimeout is how long a dispatcher holds an entry before
+// another one may take it over
+const scheduleClaimTimeout = 30 * time.Second
+
+// SchedulerService publishes scheduled executions once they are due. The
+// schedule lives in NATS KV, so pending entries survive API restarts and
+// several API replicas can run the dispatcher side by side.
+type SchedulerService struct {
+	executionRepo       repository.ExecutionRepository
+	executionStreamRepo repository.ExecutionStreamRepository
+	scheduleRepo        repository.ScheduleRepository
+	eventRepo           repository.ExecutionEventRepository
+	quotaService        *QuotaService
+	interval            time.Duration
+}
+
+func NewSchedulerService(repo repository.ExecutionRepository, streamRepo repository.ExecutionStreamRepository, scheduleRepo repository.ScheduleRepository, eventRepo repository.ExecutionEventRepository, quotaService *QuotaService, config *config.Config) *SchedulerService {
+	return &SchedulerService{
+		executionRepo:       repo,
+		executionStreamRepo: streamRepo,
+		scheduleRepo:        scheduleRepo,
+		eventRepo:           eventRepo,
+		quotaService:        quotaService,
+		interval:            config.SchedulerInterval,
+	}
+}
+
+func (s *SchedulerService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.dispatchDue(ctx); err != nil {
+				log.Printf("Error dispatching scheduled executions: %v", err)
+			}
+		}
+	}
+}
+
+func (s *SchedulerService) dispatchDue(ctx context.Context) error {
+	now := time.Now()
+	due, err := s.scheduleRepo.ListDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, scheduled := range due {
+		claimed, err := s.scheduleRepo.Claim(ctx, scheduled, now.Add(scheduleClaimTimeout))
+		if err != nil {
+			log.Printf("Error claiming scheduled execution %s: %v", scheduled.ExecutionID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		// On errors the entry stays claimed, and the next dispatcher retries
+		// it once the claim expires
+		if err := s.dispatch(ctx, scheduled); err != nil {
+			log.Printf("Error dispatching scheduled execution %s: %v", scheduled.ExecutionID, err)
+			continue
+		}
+		if err := s.scheduleRepo.Remove(ctx, scheduled); err != nil {
+			log.Printf("Error removing scheduled execution %s: %v", scheduled.ExecutionID, err)
+		}
+	}
+
+	return nil
+}
+
+// dispatch queues the execution of a claimed entry. It returns nil once the
+// entry is no longer needed.
+func (s *SchedulerService) dispatch(ctx context.Context, scheduled *entity.ScheduledExecution) error {
+	execution, err := s.executionRepo.GetByID(ctx, scheduled.ExecutionID)
+	if err != nil {
+		if errors.Is(err, entity.ErrExecutionNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	switch execution.Status {
+	case entity.StatusScheduled:
+	case entity.StatusPending:
+		// A dispatcher stopped after queueing the execution but before
+		// publishing it. Workers skip messages of executions already running.
+		if err := s.executionStreamRepo.PublishPending(execution); err != nil {
+			return err
+		}
+		log.Printf("Republished scheduled execution %s", execution.ID)
+		return nil
+	default:
+		return nil
+	}
+
+	// The execution was postponed after this entry was written
+	if execution.RunAt != nil && execution.RunAt.After(time.Now()) {
+		return s.reschedule(ctx, execution)
+	}
+
+	// A full quota postpones the execution instead of failing it
+	if err := s.quotaService.Reserve(ctx, execution); err != nil {
+		if appErrors.Code(err) == "quota_exceeded" {
+			runAt := time.Now().Add(QuotaRetryAfter)
+			execution.RunAt = &runAt
+			return s.reschedule(ctx, execution)
+		}
+		return err
+	}
+
+	execution.Status = entity.StatusPending
+	execution.Record(entity.PhaseQueued, "")
+	if err := s.executionRepo.Update(ctx, execution); err != nil {
+		s.quotaService.Release(ctx, execution)
+		return err
+	}
+
+	// The execution is pending now, so a retry of the entry republishes it
+	if err := s.executionStreamRepo.PublishPending(execution); err != nil {
+		return err
+	}
+
+	publishStatus(ctx, s.eventRepo, execution)
+	log.Printf("Dispatched scheduled execution %s", execution.ID)
+	return nil
+}
+
+// reschedule writes the entry for the new run time of the execution. The
+// claimed entry is removed only if this succeeds.
+func (s *SchedulerService) reschedule(ctx context.Context, execution *entity.Execution) error {
+	if err := s.executionRepo.Update(ctx, execution); err != nil {
+		return err
+	}
+	return s.scheduleRepo.Schedule(ctx, execution)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	"faas/internal/shared/domain/errors"
+	"sort"
+)
+
+// Executions read per page while adding up a user's usage
+const usagePageSize = 500
+
+// UsageService adds up the resource usage recorded on executions, by user or
+// by function, over a creation time range
+type UsageService struct {
+	executionRepo repository.ExecutionRepository
+}
+
+func NewUsageService(repo repository.ExecutionRepository) *UsageService {
+	return &UsageService{executionRepo: repo}
+}
+
+// GetUserUsage returns the usage of the user's own executions, by function
+func (s *UsageService) GetUserUsage(ctx context.Context, req *dto.UsageRequest, userID string) (*dto.UsageResponse, error) {
+	if req.GroupBy == "user" {
+		return nil, errors.NewAppError("invalid_group_by", "Only group_by=function is allowed for your own usage")
+	}
+	if err := validateUsageRange(req); err != nil {
+		return nil, err
+	}
+
+	usage := newUsageAggregate(req, "function")
+	query := &entity.ExecutionQuery{
+		UserID:      userID,
+		FunctionID:  req.FunctionID,
+		CreatedFrom: req.From,
+		CreatedTo:   req.To,
+		Ascending:   true,
+		Limit:       usagePageSize,
+	}
+	for {
+		page, err := s.executionRepo.List(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, execution := range page.Executions {
+			usage.add(execution)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	return usage.response(), nil
+}
+
+// GetUsage returns the usage of every user's executions, by user unless
+// grouped by function
+func (s *UsageService) GetUsage(ctx context.Context, req *dto.UsageRequest) (*dto.UsageResponse, error) {
+	if err := validateUsageRange(req); err != nil {
+		return nil, err
+	}
+
+	usage := newUsageAggregate(req, "user")
+	err := s.executionRepo.Walk(ctx, func(execution *entity.Execution) error {
+		switch {
+		case req.UserID != "" && execution.UserID != req.UserID:
+		case req.FunctionID != "" && execution.FunctionID != req.FunctionID:
+		case !req.From.IsZero() && execution.CreatedAt.Before(req.From):
+		case !req.To.IsZero() && execution.CreatedAt.After(req.To):
+		default:
+			usage.add(execution)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return usage.response(), nil
+}
+
+func validateUsageRange(req *dto.UsageRequest) error {
+	if !req.From.IsZero() && !req.To.IsZero() && req.To.Before(req.From) {
+		return errors.NewAppError("invalid_range", "to must not be before from")
+	}
+	return nil
+}
+
+// usageAggregate sums executions into one row per user or function
+type usageAggregate struct {
+	req     *dto.UsageRequest
+	groupBy string
+	rows    map[string]*dto.UsageRow
+	total   *dto.UsageRow
+}
+
+func newUsageAggregate(req *dto.UsageRequest, defaultGroupBy string) *usageAggregate {
+	groupBy := req.GroupBy
+	if groupBy == "" {
+		groupBy = defaultGroupBy
+	}
+	return &usageAggregate{
+		req:     req,
+		groupBy: groupBy,
+		rows:    make(map[string]*dto.UsageRow),
+		total:   &dto.UsageRow{},
+	}
+}
+
+// add counts executions that recorded usage; the others never ran a container
+func (a *usageAggregate) add(execution *entity.Execution) {
+	if execution.Usage == nil {
+		return
+	}
+
+	key := execution.UserID
+	if a.groupBy == "function" {
+		key = execution.FunctionID
+	}
+
+	row, ok := a.rows[key]
+	if !ok {
+		row = &dto.UsageRow{}
+		if a.groupBy == "function" {
+			row.FunctionID = key
+		} else {
+			row.UserID = key
+		}
+		a.rows[key] = row
+	}
+
+	row.Executions++
+	row.Add(execution.Usage)
+	a.total.Executions++
+	a.total.Add(execution.Usage)
+}
+
+func (a *usageAggregate) response() *dto.UsageResponse {
+	rows := make([]*dto.UsageRow, 0, len(a.rows))
+	for _, row := range a.rows {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].UserID+rows[i].FunctionID < rows[j].UserID+rows[j].FunctionID
+	})
+
+	response := &dto.UsageResponse{
+		GroupBy: a.groupBy,
+		Rows:    rows,
+		Total:   a.total,
+	}
+	if !a.req.From.IsZero() {
+		response.From = &a.req.From
+	}
+	if !a.req.To.IsZero() {
+		response.To = &a.req.To
+	}
+	return response
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	functionRepo "faas/internal/features/functions/domain/repository"
+	appErrors "faas/internal/shared/domain/errors"
+	"faas/internal/shared/infrastructure/callback"
+	"faas/internal/shared/infrastructure/config"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// Retry delays double from webhookBaseDelay up to webhookMaxDelay
+	webhookBaseDelay = 5 * time.Second
+	webhookMaxDelay  = time.Hour
+)
+
+// errPermanent marks delivery errors that retrying doesn't fix
+var errPermanent = errors.New("not retried")
+
+// WebhookService POSTs the final execution to its callback URL once it
+// finishes. The execution repositories queue the delivery in JetStream when
+// they store a final status; it is retried with backoff.
+type WebhookService struct {
+	executionRepo repository.ExecutionRepository
+	webhookRepo   repository.WebhookRepository
+	functionRepo  functionRepo.FunctionRepository
+	client        *http.Client
+	maxAttempts   int
+}
+
+func NewWebhookService(repo repository.ExecutionRepository, webhookRepo repository.WebhookRepository, functionRepo functionRepo.FunctionRepository, config *config.Config) *WebhookService {
+	return &WebhookService{
+		executionRepo: repo,
+		webhookRepo:   webhookRepo,
+		functionRepo:  functionRepo,
+		client:        callback.NewClient(config.WebhookTimeout),
+		maxAttempts:   config.WebhookMaxAttempts,
+	}
+}
+
+func (s *WebhookService) Start(ctx context.Context) {
+	if err := s.webhookRepo.Consume(ctx, s.deliver); err != nil {
+		log.Printf("Error consuming webhook deliveries: %v", err)
+	}
+}
+
+func (s *WebhookService) GetDeliveries(ctx context.Context, executionID string, userID string) (*dto.DeliveriesResponse, error) {
+	execution, err := s.executionRepo.GetByID(ctx, executionID)
+	if err != nil {
+		return nil, appErrors.NewAppError("execution_not_found", "Execution not found")
+	}
+
+	if execution.UserID != userID {
+		return nil, appErrors.NewAppError("unauthorized", "Not authorized to view this execution")
+	}
+
+	response := &dto.DeliveriesResponse{Deliveries: []*entity.WebhookDelivery{}}
+	if delivery, err := s.webhookRepo.Get(ctx, executionID); err == nil {
+		response.Deliveries = append(response.Deliveries, delivery)
+	}
+	return response, nil
+}
+
+// deliver sends one attempt and returns the delay before the next one, or 0
+// once the delivery succeeded or ran out of attempts. received counts how
+// many times the queued message was handed out.
+func (s *WebhookService) deliver(ctx context.Context, executionID string, received int) time.Duration {
+	execution, err := s.executionRepo.GetByID(ctx, executionID)
+	if errors.Is(err, entity.ErrExecutionNotFound) {
+		// The final status the delivery was queued for was never stored
+		return 0
+	}
+	if err != nil {
+		log.Printf("Error loading execution %s for its webhook: %v", executionID, err)
+		return webhookBaseDelay
+	}
+	if !execution.IsTerminal() {
+		// Queued right before the final status is stored, or the store
+		// failed and the execution runs again
+		return webhookBaseDelay
+	}
+
+	delivery, err := s.loadDelivery(ctx, execution, received)
+	if err != nil {
+		log.Printf("Error loading webhook delivery of execution %s: %v", executionID, err)
+		return webhookBaseDelay
+	}
+	if delivery == nil || delivery.Status != entity.DeliveryPending {
+		return 0
+	}
+
+	attempt := len(delivery.Attempts) + 1
+	result := &entity.DeliveryAttempt{Attempt: attempt, Time: time.Now()}
+	statusCode, err := s.send(ctx, execution, delivery)
+	result.DurationMs = time.Since(result.Time).Milliseconds()
+	result.StatusCode = statusCode
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	var retry time.Duration
+	switch {
+	case err == nil:
+		delivery.Status = entity.DeliveryDelivered
+	case errors.Is(err, errPermanent), attempt >= s.maxAttempts:
+		delivery.Status = entity.DeliveryFailed
+	default:
+		retry = webhookBackoff(attempt)
+	}
+
+	delivery.Attempts = append(delivery.Attempts, result)
+	delivery.UpdatedAt = time.Now()
+	if err := s.webhookRepo.Update(ctx, delivery); err != nil {
+		log.Printf("Error saving webhook delivery of execution %s: %v", executionID, err)
+	}
+
+	return retry
+}
+
+// loadDelivery returns the delivery of the execution, creating it the first
+// time. A message seen for the first time that finds a delivery already there
+// is a duplicate enqueue, and gets nil so that only one message sends it.
+func (s *WebhookService) loadDelivery(ctx context.Context, execution *entity.Execution, received int) (*entity.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &entity.WebhookDelivery{
+		ExecutionID: execution.ID,
+		URL:         execution.CallbackURL,
+		Status:      entity.DeliveryPending,
+		Attempts:    []*entity.DeliveryAttempt{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	created, err := s.webhookRepo.Create(ctx, delivery)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return delivery, nil
+	}
+	if received <= 1 {
+		return nil, nil
+	}
+	return s.webhookRepo.Get(ctx, execution.ID)
+}
+
+// send POSTs the execution, signed with the function's callback secret
+func (s *WebhookService) send(ctx context.Context, execution *entity.Execution, delivery *entity.WebhookDelivery) (int, error) {
+	// URLs stored before they were validated are checked here
+	if err := callback.ValidateURL(delivery.URL); err != nil {
+		return 0, fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	function, err := s.functionRepo.GetByID(ctx, execution.FunctionID)
+	if err != nil {
+		return 0, err
+	}
+	// Functions created before callbacks were signed have no secret
+	if function.CallbackSecret == "" {
+		return 0, fmt.Errorf("%w: the function has no callback secret, rotate it with POST /api/functions/%s/callback-secret", errPermanent, function.ID)
+	}
+
+	body, err := json.Marshal(dto.NewExecutionResponse(execution))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Faas-Execution-Id", execution.ID)
+	req.Header.Set("X-Faas-Timestamp", timestamp)
+	req.Header.Set("X-Faas-Signature", "sha256="+signPayload(function.CallbackSecret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("callback returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// signPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>"
+func signPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookBaseDelay
+	for i := 1; i < attempt && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
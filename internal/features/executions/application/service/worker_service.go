@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/features/executions/domain/repository"
+	functionRepo "faas/internal/features/functions/domain/repository"
+	"faas/internal/shared/infrastructure/config"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Time a replica has to recover an execution before another one may retry
+const recoveryTimeout = time.Minute
+
+// WorkerService lists the registered workers and recovers executions whose
+// worker stopped renewing their lease: they are requeued while the function
+// allows retries, and failed otherwise.
+type WorkerService struct {
+	executionRepo       repository.ExecutionRepository
+	executionStreamRepo repository.ExecutionStreamRepository
+	leaseRepo           repository.LeaseRepository
+	workerRepo          repository.WorkerRepository
+	eventRepo           repository.ExecutionEventRepository
+	logRepo             repository.ExecutionLogRepository
+	functionRepo        functionRepo.FunctionRepository
+	quotaService        *QuotaService
+	interval            time.Duration
+}
+
+func NewWorkerService(
+	repo repository.ExecutionRepository,
+	streamRepo repository.ExecutionStreamRepository,
+	leaseRepo repository.LeaseRepository,
+	workerRepo repository.WorkerRepository,
+	eventRepo repository.ExecutionEventRepository,
+	logRepo repository.ExecutionLogRepository,
+	functionRepo functionRepo.FunctionRepository,
+	quotaService *QuotaService,
+	config *config.Config,
+) *WorkerService {
+	return &WorkerService{
+		executionRepo:       repo,
+		executionStreamRepo: streamRepo,
+		leaseRepo:           leaseRepo,
+		workerRepo:          workerRepo,
+		eventRepo:           eventRepo,
+		logRepo:             logRepo,
+		functionRepo:        functionRepo,
+		quotaService:        quotaService,
+		interval:            config.LeaseReaperInterval,
+	}
+}
+
+func (s *WorkerService) ListWorkers(ctx context.Context) ([]*entity.Worker, error) {
+	return s.workerRepo.List(ctx)
+}
+
+func (s *WorkerService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reapExpiredLeases(ctx); err != nil {
+				log.Printf("Error reaping expired leases: %v", err)
+			}
+		}
+	}
+}
+
+func (s *WorkerService) reapExpiredLeases(ctx context.Context) error {
+	leases, err := s.leaseRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, lease := range leases {
+		if !lease.Expired(now) {
+			continue
+		}
+
+		// Only one API replica wins the revocation. The lease is kept, marked
+		// as recovering, until the requeue or failure is saved; if this
+		// replica stops halfway, another one retries once it expires.
+		revoked, err := s.leaseRepo.Revoke(ctx, lease, now.Add(recoveryTimeout))
+		if err != nil {
+			log.Printf("Error revoking lease of execution %s: %v", lease.ExecutionID, err)
+			continue
+		}
+		if revoked == nil {
+			continue
+		}
+
+		if err := s.recover(ctx, revoked); err != nil {
+			log.Printf("Error recovering execution %s: %v", lease.ExecutionID, err)
+			continue
+		}
+		if _, err := s.leaseRepo.Release(ctx, revoked); err != nil {
+			log.Printf("Error releasing lease of execution %s: %v", lease.ExecutionID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *WorkerService) recover(ctx context.Context, lease *entity.Lease) error {
+	execution, err := s.executionRepo.GetByID(ctx, lease.ExecutionID)
+	if errors.Is(err, entity.ErrExecutionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case execution.Status == entity.StatusPending && execution.Attempt == lease.Attempt+1:
+		// An earlier recovery saved the requeue but may not have published
+		// it. Workers skip the duplicate if it did.
+		return s.executionStreamRepo.PublishPending(execution)
+	case execution.Status != entity.StatusRunning || execution.Attempt != lease.Attempt:
+		return nil
+	}
+
+	maxRetries := 0
+	if function, err := s.functionRepo.GetByID(ctx, execution.FunctionID); err == nil {
+		maxRetries = function.MaxRetries
+	}
+
+	reason := fmt.Sprintf("lease of worker %s expired", lease.WorkerID)
+	execution.Lease = nil
+
+	if execution.Attempt < maxRetries {
+		execution.Attempt++
+		execution.Status = entity.StatusPending
+		execution.StartedAt = nil
+		execution.Progress = nil
+		execution.StatusMessage = ""
+		execution.Record(entity.PhaseRequeued, reason)
+		if err := s.executionRepo.Update(ctx, execution); err != nil {
+			return err
+		}
+		if err := s.executionStreamRepo.PublishPending(execution); err != nil {
+			return err
+		}
+		publishStatus(ctx, s.eventRepo, execution)
+		log.Printf("Requeued execution %s (attempt %d): %s", execution.ID, execution.Attempt, reason)
+		return nil
+	}
+
+	now := time.Now()
+	execution.Status = entity.StatusFailed
+	execution.Error = "worker lost: " + reason
+	execution.FailureClass = entity.FailureInfrastructure
+	execution.CompletedAt = &now
+	if err := s.executionRepo.Update(ctx, execution); err != nil {
+		return err
+	}
+	publishStatus(ctx, s.eventRepo, execution)
+	s.quotaService.Release(ctx, execution)
+	// The lost worker never marked the end of the logs, which followers wait for
+	if err := s.logRepo.End(ctx, execution.ID); err != nil {
+		log.Printf("Error ending logs of execution %s: %v", execution.ID, err)
+	}
+	log.Printf("Failed execution %s: %s", execution.ID, reason)
+	return nil
+}
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// CacheEntry is the result of a successful execution, reused by later
+// executions with the same cache key
+type CacheEntry struct {
+	Key         string    `json:"key"`
+	ExecutionID string    `json:"execution_id"`
+	FunctionID  string    `json:"function_id"`
+	Output      string    `json:"output"`
+	ImageDigest string    `json:"image_digest,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (e *CacheEntry) Expired(ttl time.Duration, now time.Time) bool {
+	return now.After(e.CreatedAt.Add(ttl))
+}
@@ -1,25 +1,93 @@
 package entity
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrExecutionNotFound is returned when looking up an execution that doesn't exist
+var ErrExecutionNotFound = errors.New("execution not found")
 
 type ExecutionStatus string
 
 const (
+	StatusScheduled ExecutionStatus = "scheduled"
 	StatusPending   ExecutionStatus = "pending"
 	StatusRunning   ExecutionStatus = "running"
 	StatusCompleted ExecutionStatus = "completed"
 	StatusFailed    ExecutionStatus = "failed"
 )
 
+// ExecutionPriority selects the queue an execution is published to
+type ExecutionPriority string
+
+const (
+	PriorityHigh   ExecutionPriority = "high"
+	PriorityNormal ExecutionPriority = "normal"
+	PriorityBatch  ExecutionPriority = "batch"
+)
+
+// Priorities lists every priority class, from most to least urgent
+var Priorities = []ExecutionPriority{PriorityHigh, PriorityNormal, PriorityBatch}
+
+func (p ExecutionPriority) IsValid() bool {
+	for _, priority := range Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
 type Execution struct {
-	ID          string          `json:"id"`
-	FunctionID  string          `json:"function_id"`
-	UserID      string          `json:"user_id"`
-	Status      ExecutionStatus `json:"status"`
-	Input       string          `json:"input"`
-	Output      string          `json:"output,omitempty"`
-	Error       string          `json:"error,omitempty"`
-	CreatedAt   time.Time       `json:"created_at"`
-	StartedAt   *time.Time      `json:"started_at,omitempty"`
-	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	ID                string            `json:"id"`
+	FunctionID        string            `json:"function_id"`
+	UserID            string            `json:"user_id"`
+	Status            ExecutionStatus   `json:"status"`
+	Priority          ExecutionPriority `json:"priority,omitempty"`
+	Input             string            `json:"input"`
+	Output            string            `json:"output,omitempty"`
+	OutputRef         *OutputRef        `json:"output_ref,omitempty"`
+	Error             string            `json:"error,omitempty"`
+	FailureClass      FailureClass      `json:"failure_class,omitempty"`
+	CallbackURL       string            `json:"callback_url,omitempty"`
+	RerunOf           string            `json:"rerun_of,omitempty"`
+	ParentExecutionID string            `json:"parent_execution_id,omitempty"`
+	RootExecutionID   string            `json:"root_execution_id,omitempty"`
+	CallDepth         int               `json:"call_depth,omitempty"`
+	PinnedImage       string            `json:"pinned_image,omitempty"`
+	ImageDigest       string            `json:"image_digest,omitempty"`
+	SandboxProfile    string            `json:"sandbox_profile,omitempty"`
+	Attempt           int               `json:"attempt,omitempty"`
+	Lease             *Lease            `json:"lease,omitempty"`
+	Usage             *ResourceUsage    `json:"usage,omitempty"`
+	CacheKey          string            `json:"cache_key,omitempty"`
+	CacheHit          bool              `json:"cache_hit,omitempty"`
+	CachedFrom        string            `json:"cached_from,omitempty"`
+	TraceID           string            `json:"trace_id,omitempty"`
+	Progress          *int              `json:"progress,omitempty"`
+	StatusMessage     string            `json:"status_message,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	RunAt             *time.Time        `json:"run_at,omitempty"`
+	StartedAt         *time.Time        `json:"started_at,omitempty"`
+	CompletedAt       *time.Time        `json:"completed_at,omitempty"`
+	Timeline          []*TimelineEvent  `json:"timeline,omitempty"`
+}
+
+// RootID returns the execution that started the call tree of this one
+func (e *Execution) RootID() string {
+	if e.RootExecutionID != "" {
+		return e.RootExecutionID
+	}
+	return e.ID
+}
+
+// TraceIDOrDefault returns the trace ID, derived from the execution ID for
+// executions created before trace IDs were assigned
+func (e *Execution) TraceIDOrDefault() string {
+	if e.TraceID != "" {
+		return e.TraceID
+	}
+	return strings.ReplaceAll(e.ID, "-", "")
 }
@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+const (
+	EventTypeStatus   = "status"
+	EventTypeProgress = "progress"
+)
+
+// ExecutionEvent is published whenever something happens to an execution
+type ExecutionEvent struct {
+	Type          string          `json:"type"`
+	ExecutionID   string          `json:"execution_id"`
+	FunctionID    string          `json:"function_id"`
+	UserID        string          `json:"-"`
+	Status        ExecutionStatus `json:"status"`
+	FailureClass  FailureClass    `json:"failure_class,omitempty"`
+	Progress      *int            `json:"progress,omitempty"`
+	StatusMessage string          `json:"status_message,omitempty"`
+	Time          time.Time       `json:"time"`
+}
+
+// NewStatusEvent describes the current status of the execution
+func NewStatusEvent(execution *Execution) *ExecutionEvent {
+	return &ExecutionEvent{
+		Type:         EventTypeStatus,
+		ExecutionID:  execution.ID,
+		FunctionID:   execution.FunctionID,
+		UserID:       execution.UserID,
+		Status:       execution.Status,
+		FailureClass: execution.FailureClass,
+		Time:         time.Now(),
+	}
+}
+
+// NewProgressEvent describes the progress reported by a running execution
+func NewProgressEvent(execution *Execution) *ExecutionEvent {
+	return &ExecutionEvent{
+		Type:          EventTypeProgress,
+		ExecutionID:   execution.ID,
+		FunctionID:    execution.FunctionID,
+		UserID:        execution.UserID,
+		Status:        execution.Status,
+		Progress:      execution.Progress,
+		StatusMessage: execution.StatusMessage,
+		Time:          time.Now(),
+	}
+}
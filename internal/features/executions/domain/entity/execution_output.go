@@ -0,0 +1,8 @@
+package entity
+
+// OutputRef points to an output too large to be stored in the execution
+// record. The output itself is kept in the outputs object store.
+type OutputRef struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
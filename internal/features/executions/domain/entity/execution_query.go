@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// ExecutionQuery selects a page of a user's executions, newest first unless
+// Ascending is set
+type ExecutionQuery struct {
+	UserID       string
+	FunctionID   string
+	Status       ExecutionStatus
+	FailureClass FailureClass
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	Ascending    bool
+	Cursor       string
+	Limit        int
+}
+
+type ExecutionPage struct {
+	Executions []*Execution
+	NextCursor string
+}
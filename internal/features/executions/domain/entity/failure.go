@@ -0,0 +1,13 @@
+package entity
+
+// FailureClass groups failed executions by cause
+type FailureClass string
+
+const (
+	// The function did not finish before its deadline
+	FailureTimeout FailureClass = "timeout"
+	// The function exited with a non-zero code
+	FailureFunctionError FailureClass = "function_error"
+	// The platform could not run the function (image pull, container, secrets...)
+	FailureInfrastructure FailureClass = "infrastructure"
+)
@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLeaseLost is returned when renewing a lease another party has revoked,
+// or acquiring one another worker holds
+var ErrLeaseLost = errors.New("execution lease lost")
+
+// Lease marks which worker runs an execution. The worker renews it while the
+// container runs; once it expires the execution is recovered. While that
+// happens the lease is kept, marked as recovering, so a recovery interrupted
+// halfway is retried once it expires again.
+type Lease struct {
+	ExecutionID string    `json:"execution_id"`
+	WorkerID    string    `json:"worker_id"`
+	Attempt     int       `json:"attempt"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Recovering  bool      `json:"recovering,omitempty"`
+}
+
+func (l *Lease) Expired(now time.Time) bool {
+	return now.After(l.ExpiresAt)
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+const (
+	LogStreamStdout = "stdout"
+	LogStreamStderr = "stderr"
+)
+
+// LogEntry is one line written by a function
+type LogEntry struct {
+	Offset int       `json:"offset"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Line   string    `json:"line"`
+}
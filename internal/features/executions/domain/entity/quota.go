@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// QuotaSlot is a concurrency slot an execution holds in a counter
+type QuotaSlot struct {
+	Key         string
+	ExecutionID string
+	ReservedAt  time.Time
+}
+
+// Quota keys name the counters concurrency slots are taken from
+func UserQuotaKey(userID string) string {
+	return "user." + userID
+}
+
+func FunctionQuotaKey(functionID string) string {
+	return "function." + functionID
+}
+
+// QuotaKeys returns every counter the execution may hold a slot in
+func (e *Execution) QuotaKeys() []string {
+	return []string{UserQuotaKey(e.UserID), FunctionQuotaKey(e.FunctionID)}
+}
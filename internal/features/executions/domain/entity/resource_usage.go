@@ -0,0 +1,26 @@
+package entity
+
+// ResourceUsage is what an execution consumed, sampled from the container
+// stats while it ran. Counters are cumulative over the run.
+type ResourceUsage struct {
+	CPUSeconds      float64 `json:"cpu_seconds"`
+	PeakMemoryBytes uint64  `json:"peak_memory_bytes"`
+	NetworkRxBytes  uint64  `json:"network_rx_bytes"`
+	NetworkTxBytes  uint64  `json:"network_tx_bytes"`
+	BlockReadBytes  uint64  `json:"block_read_bytes"`
+	BlockWriteBytes uint64  `json:"block_write_bytes"`
+	WallTimeMs      int64   `json:"wall_time_ms"`
+}
+
+// Add sums other into u, keeping the highest memory peak
+func (u *ResourceUsage) Add(other *ResourceUsage) {
+	u.CPUSeconds += other.CPUSeconds
+	if other.PeakMemoryBytes > u.PeakMemoryBytes {
+		u.PeakMemoryBytes = other.PeakMemoryBytes
+	}
+	u.NetworkRxBytes += other.NetworkRxBytes
+	u.NetworkTxBytes += other.NetworkTxBytes
+	u.BlockReadBytes += other.BlockReadBytes
+	u.BlockWriteBytes += other.BlockWriteBytes
+	u.WallTimeMs += other.WallTimeMs
+}
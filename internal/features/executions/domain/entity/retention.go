@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// BucketStats describes the storage used by the executions bucket
+type BucketStats struct {
+	Values uint64 `json:"values"`
+	Bytes  uint64 `json:"bytes"`
+}
+
+// PurgeStats summarises a retention run
+type PurgeStats struct {
+	StartedAt      time.Time `json:"started_at"`
+	DurationMs     int64     `json:"duration_ms"`
+	Scanned        int       `json:"scanned"`
+	Purged         int       `json:"purged"`
+	Archived       int       `json:"archived"`
+	ArchiveObjects []string  `json:"archive_objects,omitempty"`
+	Errors         int       `json:"errors"`
+}
+
+// IsTerminal reports whether the execution reached a final status
+func (e *Execution) IsTerminal() bool {
+	return e.Status == StatusCompleted || e.Status == StatusFailed
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// ScheduledExecution is an entry of the schedule, waiting to be dispatched.
+// A dispatcher claims it until ClaimedUntil and removes it once the execution
+// is queued; claims that expire are picked up again.
+type ScheduledExecution struct {
+	ExecutionID  string     `json:"execution_id"`
+	RunAt        time.Time  `json:"run_at"`
+	ClaimedUntil *time.Time `json:"claimed_until,omitempty"`
+	Key          string     `json:"-"`
+	Revision     uint64     `json:"-"`
+}
@@ -0,0 +1,79 @@
+package entity
+
+import "time"
+
+type Phase string
+
+const (
+	PhaseQueued           Phase = "queued"
+	PhasePickedUp         Phase = "picked_up"
+	PhasePullStarted      Phase = "pull_started"
+	PhasePullFinished     Phase = "pull_finished"
+	PhaseContainerCreated Phase = "container_created"
+	PhaseStarted          Phase = "started"
+	PhaseExited           Phase = "exited"
+	PhaseOutputCollected  Phase = "output_collected"
+	PhasePersisted        Phase = "persisted"
+	PhaseRequeued         Phase = "requeued"
+	PhaseCacheHit         Phase = "cache_hit"
+)
+
+// TimelineEvent marks when an execution reached a phase of its lifecycle
+type TimelineEvent struct {
+	Phase  Phase     `json:"phase"`
+	Time   time.Time `json:"time"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// ExecutionTimings are derived from the timeline, in milliseconds. A timing
+// is nil while one of its phases is missing.
+type ExecutionTimings struct {
+	QueueMs     *int64 `json:"queue_ms,omitempty"`
+	PullMs      *int64 `json:"pull_ms,omitempty"`
+	ColdStartMs *int64 `json:"cold_start_ms,omitempty"`
+	RunMs       *int64 `json:"run_ms,omitempty"`
+	TotalMs     *int64 `json:"total_ms,omitempty"`
+}
+
+// Record appends a phase to the timeline
+func (e *Execution) Record(phase Phase, detail string) {
+	e.Timeline = append(e.Timeline, &TimelineEvent{
+		Phase:  phase,
+		Time:   time.Now(),
+		Detail: detail,
+	})
+}
+
+// Timings derives the phase durations. When a phase was reached more than
+// once (a requeued execution) the last occurrence counts.
+func (e *Execution) Timings() *ExecutionTimings {
+	if len(e.Timeline) == 0 {
+		return nil
+	}
+
+	reached := make(map[Phase]time.Time, len(e.Timeline))
+	for _, event := range e.Timeline {
+		reached[event.Phase] = event.Time
+	}
+
+	between := func(from, to Phase) *int64 {
+		start, ok := reached[from]
+		if !ok {
+			return nil
+		}
+		end, ok := reached[to]
+		if !ok || end.Before(start) {
+			return nil
+		}
+		ms := end.Sub(start).Milliseconds()
+		return &ms
+	}
+
+	return &ExecutionTimings{
+		QueueMs:     between(PhaseQueued, PhasePickedUp),
+		PullMs:      between(PhasePullStarted, PhasePullFinished),
+		ColdStartMs: between(PhasePickedUp, PhaseStarted),
+		RunMs:       between(PhaseStarted, PhaseExited),
+		TotalMs:     between(PhaseQueued, PhasePersisted),
+	}
+}
@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is the completion callback of one execution
+type WebhookDelivery struct {
+	ExecutionID string             `json:"execution_id"`
+	URL         string             `json:"url"`
+	Status      DeliveryStatus     `json:"status"`
+	Attempts    []*DeliveryAttempt `json:"attempts"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+
+	// Revision is the stored version the delivery was read at
+	Revision uint64 `json:"-"`
+}
+
+// DeliveryAttempt records one POST to the callback URL
+type DeliveryAttempt struct {
+	Attempt    int       `json:"attempt"`
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// IsTerminal reports whether the event announces a final status
+func (e *ExecutionEvent) IsTerminal() bool {
+	return e.Type == EventTypeStatus && (e.Status == StatusCompleted || e.Status == StatusFailed)
+}
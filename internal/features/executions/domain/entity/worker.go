@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// Worker is a worker process, as reported by its last heartbeat
+type Worker struct {
+	ID            string    `json:"id"`
+	Runtime       string    `json:"runtime,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Executions    []string  `json:"executions"`
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type CacheRepository interface {
+	// Get returns nil when there is no entry for the key
+	Get(ctx context.Context, key string) (*entity.CacheEntry, error)
+	Put(ctx context.Context, entry *entity.CacheEntry) error
+	// LatestDigest returns the image digest the last run of the function
+	// resolved, or "" when none was recorded
+	LatestDigest(ctx context.Context, functionID string) (string, error)
+	SetLatestDigest(ctx context.Context, functionID string, digest string) error
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type ExecutionArchiveRepository interface {
+	// Archive stores the executions as one object and returns its name
+	Archive(ctx context.Context, executions []*entity.Execution) (string, error)
+}
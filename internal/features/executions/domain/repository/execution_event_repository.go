@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type ExecutionEventRepository interface {
+	Publish(ctx context.Context, event *entity.ExecutionEvent) error
+	// Subscribe delivers the events of the user published from now on,
+	// optionally narrowed to one function and/or execution. The channel is
+	// closed when ctx is done.
+	Subscribe(ctx context.Context, userID string, functionID string, executionID string) (<-chan *entity.ExecutionEvent, error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type ExecutionLogRepository interface {
+	Append(ctx context.Context, executionID string, entry *entity.LogEntry) error
+	// End marks that no more lines will be appended for the execution
+	End(ctx context.Context, executionID string) error
+	// List returns every stored line of the execution in order
+	List(ctx context.Context, executionID string) ([]*entity.LogEntry, error)
+	// Follow delivers the stored lines and then new ones as they are appended.
+	// The channel is closed after the end marker or when ctx is done.
+	Follow(ctx context.Context, executionID string) (<-chan *entity.LogEntry, error)
+	// Delete removes every stored line of the execution
+	Delete(ctx context.Context, executionID string) error
+}
@@ -9,7 +9,12 @@ import (
 type ExecutionRepository interface {
 	Save(ctx context.Context, execution *entity.Execution) error
 	GetByID(ctx context.Context, id string) (*entity.Execution, error)
-	ListByUserID(ctx context.Context, userID string) ([]*entity.Execution, error)
+	List(ctx context.Context, query *entity.ExecutionQuery) (*entity.ExecutionPage, error)
+	// ListChildren returns the executions started by parentID, oldest first
+	ListChildren(ctx context.Context, parentID string) ([]*entity.Execution, error)
 	Update(ctx context.Context, execution *entity.Execution) error
-	GetActiveExecutionCount(ctx context.Context, userID string) (int, error)
+	// Walk lists every execution key and loads the executions one at a time
+	Walk(ctx context.Context, fn func(execution *entity.Execution) error) error
+	Delete(ctx context.Context, id string) error
+	Stats(ctx context.Context) (*entity.BucketStats, error)
 }
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type LeaseRepository interface {
+	// Acquire stores the lease, replacing those of earlier attempts or being
+	// recovered. It returns entity.ErrLeaseLost if another worker already
+	// holds the same attempt.
+	Acquire(ctx context.Context, lease *entity.Lease) error
+	// Renew extends the lease, or returns entity.ErrLeaseLost if it was
+	// revoked or taken over in the meantime
+	Renew(ctx context.Context, lease *entity.Lease) error
+	// Revoke marks the lease as recovering until recoverBy if it is still
+	// the one given, returning the recovering lease, or nil if it changed
+	Revoke(ctx context.Context, lease *entity.Lease, recoverBy time.Time) (*entity.Lease, error)
+	// Release removes the lease if the same worker still holds it for the
+	// same attempt, reporting whether it did
+	Release(ctx context.Context, lease *entity.Lease) (bool, error)
+	List(ctx context.Context) ([]*entity.Lease, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"io"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type OutputRepository interface {
+	Put(ctx context.Context, executionID string, output []byte) (*entity.OutputRef, error)
+	Get(ctx context.Context, executionID string) (io.ReadCloser, error)
+	Delete(ctx context.Context, executionID string) error
+}
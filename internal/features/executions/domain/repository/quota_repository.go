@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type QuotaRepository interface {
+	// Reserve atomically takes a slot of the counter for the execution. It
+	// returns false if limit slots are already taken. Reserving twice for
+	// the same execution is a no-op.
+	Reserve(ctx context.Context, key string, executionID string, limit int) (bool, error)
+	// Release frees the slot of the execution, if it holds one
+	Release(ctx context.Context, key string, executionID string) error
+	// Slots returns every slot taken, across all counters
+	Slots(ctx context.Context) ([]*entity.QuotaSlot, error)
+}
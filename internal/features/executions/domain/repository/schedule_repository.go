@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type ScheduleRepository interface {
+	Schedule(ctx context.Context, execution *entity.Execution) error
+	// ListDue returns the entries due at now that nobody holds a claim on
+	ListDue(ctx context.Context, now time.Time) ([]*entity.ScheduledExecution, error)
+	// Claim marks the entry as taken until the given time, only if nobody else
+	// did, so a single dispatcher wins
+	Claim(ctx context.Context, scheduled *entity.ScheduledExecution, until time.Time) (bool, error)
+	// Remove deletes a claimed entry once its execution was dispatched
+	Remove(ctx context.Context, scheduled *entity.ScheduledExecution) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type WebhookRepository interface {
+	// Create stores a new delivery. It returns false if the execution
+	// already has one.
+	Create(ctx context.Context, delivery *entity.WebhookDelivery) (bool, error)
+	// Update fails if the delivery changed since it was read
+	Update(ctx context.Context, delivery *entity.WebhookDelivery) error
+	Get(ctx context.Context, executionID string) (*entity.WebhookDelivery, error)
+	// Consume hands queued deliveries to handler, several at once, until ctx
+	// is done, with the times each message was handed out. The handler returns how long to wait
+	// before retrying, or 0 when done. Deliveries are queued by the execution
+	// repositories when they store a final status.
+	Consume(ctx context.Context, handler func(ctx context.Context, executionID string, received int) time.Duration) error
+}
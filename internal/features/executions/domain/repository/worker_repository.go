@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"faas/internal/features/executions/domain/entity"
+)
+
+type WorkerRepository interface {
+	Heartbeat(ctx context.Context, worker *entity.Worker) error
+	Deregister(ctx context.Context, workerID string) error
+	List(ctx context.Context) ([]*entity.Worker, error)
+}
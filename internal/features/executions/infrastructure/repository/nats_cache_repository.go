@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+type NatsCacheRepository struct {
+	kv nats.KeyValue
+}
+
+func NewNatsCacheRepository(js nats.JetStreamContext) (*NatsCacheRepository, error) {
+	kv, err := js.KeyValue(nats.CACHE_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsCacheRepository{kv: nats.NewKeyValueAdapter(kv)}, nil
+}
+
+func (r *NatsCacheRepository) Get(ctx context.Context, key string) (*entity.CacheEntry, error) {
+	entry, err := r.kv.Get(key)
+	if errors.Is(err, natspkg.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cached entity.CacheEntry
+	if err := json.Unmarshal(entry.Value(), &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+func (r *NatsCacheRepository) Put(ctx context.Context, entry *entity.CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.kv.Put(entry.Key, data)
+	return err
+}
+
+func (r *NatsCacheRepository) LatestDigest(ctx context.Context, functionID string) (string, error) {
+	entry, err := r.kv.Get(latestDigestKey(functionID))
+	if errors.Is(err, natspkg.ErrKeyNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(entry.Value()), nil
+}
+
+func (r *NatsCacheRepository) SetLatestDigest(ctx context.Context, functionID string, digest string) error {
+	_, err := r.kv.Put(latestDigestKey(functionID), []byte(digest))
+	return err
+}
+
+// latestDigestKey cannot clash with cache keys, which are hex hashes
+func latestDigestKey(functionID string) string {
+	return "digests." + functionID
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"fmt"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+type NatsExecutionArchiveRepository struct {
+	store natspkg.ObjectStore
+}
+
+func NewNatsExecutionArchiveRepository(js nats.JetStreamContext) (*NatsExecutionArchiveRepository, error) {
+	store, err := js.ObjectStore(nats.ARCHIVE_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsExecutionArchiveRepository{store: store}, nil
+}
+
+func (r *NatsExecutionArchiveRepository) Archive(ctx context.Context, executions []*entity.Execution) (string, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, execution := range executions {
+		if err := encoder.Encode(execution); err != nil {
+			return "", err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	name := fmt.Sprintf("executions/%s/%d.jsonl.gz", now.Format("2006-01-02"), now.UnixNano())
+	_, err := r.store.Put(&natspkg.ObjectMeta{
+		Name:        name,
+		Description: fmt.Sprintf("%d archived executions", len(executions)),
+	}, &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
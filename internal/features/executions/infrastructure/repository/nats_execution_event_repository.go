@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+type NatsExecutionEventRepository struct {
+	js nats.JetStreamContext
+}
+
+func NewNatsExecutionEventRepository(js nats.JetStreamContext) *NatsExecutionEventRepository {
+	return &NatsExecutionEventRepository{js: js}
+}
+
+func (r *NatsExecutionEventRepository) Publish(ctx context.Context, event *entity.ExecutionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = r.js.Publish(nats.EventSubject(event.UserID, event.FunctionID, event.ExecutionID), data, natspkg.Context(ctx))
+	return err
+}
+
+func (r *NatsExecutionEventRepository) Subscribe(ctx context.Context, userID string, functionID string, executionID string) (<-chan *entity.ExecutionEvent, error) {
+	if functionID == "" {
+		functionID = "*"
+	}
+	if executionID == "" {
+		executionID = "*"
+	}
+
+	subject := nats.EventSubject(userID, functionID, executionID)
+	sub, err := r.js.SubscribeSync(subject, natspkg.OrderedConsumer(), natspkg.DeliverNew(), natspkg.BindStream(nats.EVENTS_STREAM))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan *entity.ExecutionEvent)
+	go func() {
+		defer close(events)
+		defer sub.Unsubscribe()
+
+		for {
+			msg, err := sub.NextMsgWithContext(ctx)
+			if err != nil {
+				return
+			}
+
+			var event entity.ExecutionEvent
+			if err := json.Unmarshal(msg.Data, &event); err != nil {
+				continue
+			}
+
+			select {
+			case events <- &event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+const (
+	// How long to wait for the next stored line before giving up on a read
+	logReadTimeout = 5 * time.Second
+
+	// Header of the empty message published once an execution has finished logging
+	logEndHeader = "Faas-Log-End"
+)
+
+type NatsExecutionLogRepository struct {
+	js nats.JetStreamContext
+}
+
+func NewNatsExecutionLogRepository(js nats.JetStreamContext) *NatsExecutionLogRepository {
+	return &NatsExecutionLogRepository{js: js}
+}
+
+func (r *NatsExecutionLogRepository) Append(ctx context.Context, executionID string, entry *entity.LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.js.Publish(nats.LogSubject(executionID), data, natspkg.Context(ctx))
+	return err
+}
+
+func (r *NatsExecutionLogRepository) End(ctx context.Context, executionID string) error {
+	msg := natspkg.NewMsg(nats.LogSubject(executionID))
+	msg.Header.Set(logEndHeader, "true")
+	_, err := r.js.PublishMsg(msg, natspkg.Context(ctx))
+	return err
+}
+
+func (r *NatsExecutionLogRepository) List(ctx context.Context, executionID string) ([]*entity.LogEntry, error) {
+	subject := nats.LogSubject(executionID)
+
+	// The last stored message tells us when we have read everything
+	last, err := r.js.GetLastMsg(nats.LOGS_STREAM, subject)
+	if err != nil {
+		if errors.Is(err, natspkg.ErrMsgNotFound) {
+			return []*entity.LogEntry{}, nil
+		}
+		return nil, err
+	}
+
+	sub, err := r.js.SubscribeSync(subject, natspkg.OrderedConsumer(), natspkg.DeliverAll(), natspkg.BindStream(nats.LOGS_STREAM))
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
+	entries := []*entity.LogEntry{}
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, logReadTimeout)
+		msg, err := sub.NextMsgWithContext(readCtx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		if entry, ok := decodeLogEntry(msg); ok {
+			entry.Offset = len(entries)
+			entries = append(entries, entry)
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, err
+		}
+		if meta.Sequence.Stream >= last.Sequence {
+			return entries, nil
+		}
+	}
+}
+
+func (r *NatsExecutionLogRepository) Follow(ctx context.Context, executionID string) (<-chan *entity.LogEntry, error) {
+	sub, err := r.js.SubscribeSync(nats.LogSubject(executionID), natspkg.OrderedConsumer(), natspkg.DeliverAll(), natspkg.BindStream(nats.LOGS_STREAM))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(chan *entity.LogEntry)
+	go func() {
+		defer close(entries)
+		defer sub.Unsubscribe()
+
+		offset := 0
+		for {
+			msg, err := sub.NextMsgWithContext(ctx)
+			if err != nil {
+				return
+			}
+			if msg.Header.Get(logEndHeader) != "" {
+				return
+			}
+
+			entry, ok := decodeLogEntry(msg)
+			if !ok {
+				continue
+			}
+			entry.Offset = offset
+			offset++
+
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return entries, nil
+}
+
+func (r *NatsExecutionLogRepository) Delete(ctx context.Context, executionID string) error {
+	return r.js.PurgeStream(nats.LOGS_STREAM, &natspkg.StreamPurgeRequest{Subject: nats.LogSubject(executionID)}, natspkg.Context(ctx))
+}
+
+func decodeLogEntry(msg *natspkg.Msg) (*entity.LogEntry, bool) {
+	if msg.Header.Get(logEndHeader) != "" {
+		return nil, false
+	}
+	var entry entity.LogEntry
+	if err := json.Unmarshal(msg.Data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
@@ -2,13 +2,50 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"faas/internal/features/executions/domain/entity"
+	appErrors "faas/internal/shared/domain/errors"
 	"faas/internal/shared/infrastructure/nats"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+// Index keys look like "user.<user_id>.<day>.<inverted_created_at>.<execution_id>",
+// bucketed by the UTC day the execution was created. The inverted timestamp
+// makes lexical order newest first. Executions started by another one are
+// also indexed under "children.<parent_execution_id>". The days with entries
+// of each owner are listed under "days.<index>.<owner>", so a page only reads
+// the days it needs.
+const (
+	userIndex     = "user"
+	functionIndex = "function"
+	childrenIndex = "children"
+
+	indexDayAttempts = 5
 )
 
+// indexEntry is the value of an index key, enough to filter a listing
+// without loading the executions
+type indexEntry struct {
+	UserID       string                 `json:"user_id"`
+	Status       entity.ExecutionStatus `json:"status"`
+	FailureClass entity.FailureClass    `json:"failure_class,omitempty"`
+}
+
 type NatsExecutionRepository struct {
-	kv nats.KeyValue
+	js    nats.JetStreamContext
+	kv    nats.KeyValue
+	index nats.KeyValue
 }
 
 func NewNatsExecutionRepository(js nats.JetStreamContext) (*NatsExecutionRepository, error) {
@@ -16,10 +53,33 @@ func NewNatsExecutionRepository(js nats.JetStreamContext) (*NatsExecutionReposit
 	if err != nil {
 		return nil, err
 	}
-	return &NatsExecutionRepository{kv: nats.NewKeyValueAdapter(kv)}, nil
+	index, err := js.KeyValue(nats.INDEX_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsExecutionRepository{
+		js:    js,
+		kv:    nats.NewKeyValueAdapter(kv),
+		index: nats.NewKeyValueAdapter(index),
+	}, nil
 }
 
 func (r *NatsExecutionRepository) Save(ctx context.Context, execution *entity.Execution) error {
+	if err := r.put(execution); err != nil {
+		return err
+	}
+	return r.addToIndex(execution)
+}
+
+func (r *NatsExecutionRepository) put(execution *entity.Execution) error {
+	// The callback is queued before the final status is stored, so a stored
+	// final status always has its callback queued
+	if execution.IsTerminal() && execution.CallbackURL != "" {
+		if err := nats.EnqueueWebhook(r.js, execution.ID); err != nil {
+			return err
+		}
+	}
+
 	data, err := json.Marshal(execution)
 	if err != nil {
 		return err
@@ -31,6 +91,9 @@ func (r *NatsExecutionRepository) Save(ctx context.Context, execution *entity.Ex
 func (r *NatsExecutionRepository) GetByID(ctx context.Context, id string) (*entity.Execution, error) {
 	data, err := r.kv.Get(id)
 	if err != nil {
+		if errors.Is(err, natspkg.ErrKeyNotFound) {
+			return nil, entity.ErrExecutionNotFound
+		}
 		return nil, err
 	}
 
@@ -41,14 +104,163 @@ func (r *NatsExecutionRepository) GetByID(ctx context.Context, id string) (*enti
 	return &execution, nil
 }
 
-func (r *NatsExecutionRepository) ListByUserID(ctx context.Context, userID string) ([]*entity.Execution, error) {
-	keys, err := r.kv.Keys()
+func (r *NatsExecutionRepository) List(ctx context.Context, query *entity.ExecutionQuery) (*entity.ExecutionPage, error) {
+	index, owner := userIndex, query.UserID
+	if query.FunctionID != "" {
+		index, owner = functionIndex, query.FunctionID
+	}
+
+	cursor, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	cursorAt, _, hasCursor := parseIndexSuffix(cursor)
+
+	days, _, err := r.indexDays(index, owner)
+	if err != nil {
+		return nil, err
+	}
+	if !query.Ascending {
+		slices.Reverse(days)
+	}
+
+	page := &entity.ExecutionPage{Executions: []*entity.Execution{}}
+	var last string
+	var empty []int64
+	today := indexDay(time.Now())
+
+days:
+	for _, day := range days {
+		if hasCursor && (query.Ascending && day < indexDay(cursorAt) || !query.Ascending && day > indexDay(cursorAt)) {
+			continue
+		}
+		if !query.CreatedFrom.IsZero() && day < indexDay(query.CreatedFrom) {
+			continue
+		}
+		if !query.CreatedTo.IsZero() && day > indexDay(query.CreatedTo) {
+			continue
+		}
+
+		entries, err := r.indexEntries(ctx, indexDayPrefix(index, owner, day))
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 && day < today {
+			empty = append(empty, day)
+			continue
+		}
+
+		suffixes := make([]string, 0, len(entries))
+		for suffix := range entries {
+			suffixes = append(suffixes, suffix)
+		}
+		sort.Strings(suffixes)
+		if query.Ascending {
+			sort.Sort(sort.Reverse(sort.StringSlice(suffixes)))
+		}
+
+		for _, suffix := range suffixes {
+			if cursor != "" && (query.Ascending && suffix >= cursor || !query.Ascending && suffix <= cursor) {
+				continue
+			}
+
+			createdAt, executionID, ok := parseIndexSuffix(suffix)
+			if !ok {
+				continue
+			}
+			if !query.CreatedFrom.IsZero() && createdAt.Before(query.CreatedFrom) {
+				continue
+			}
+			if !query.CreatedTo.IsZero() && createdAt.After(query.CreatedTo) {
+				continue
+			}
+
+			entry := entries[suffix]
+			if entry.UserID != query.UserID {
+				continue
+			}
+			if query.Status != "" && entry.Status != query.Status {
+				continue
+			}
+			if query.FailureClass != "" && entry.FailureClass != query.FailureClass {
+				continue
+			}
+
+			// One more match than requested means there is a next page
+			if len(page.Executions) == query.Limit {
+				page.NextCursor = encodeCursor(last)
+				break days
+			}
+
+			execution, err := r.GetByID(ctx, executionID)
+			if err != nil {
+				continue
+			}
+			page.Executions = append(page.Executions, execution)
+			last = suffix
+		}
+	}
+
+	r.pruneIndexDays(index, owner, empty)
+	return page, nil
+}
+
+// ListChildren returns the executions started by parentID, oldest first
+func (r *NatsExecutionRepository) ListChildren(ctx context.Context, parentID string) ([]*entity.Execution, error) {
+	days, _, err := r.indexDays(childrenIndex, parentID)
 	if err != nil {
 		return nil, err
 	}
 
-	var executions []*entity.Execution
+	var suffixes []string
+	for _, day := range days {
+		entries, err := r.indexEntries(ctx, indexDayPrefix(childrenIndex, parentID, day))
+		if err != nil {
+			return nil, err
+		}
+		for suffix := range entries {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(suffixes)))
+
+	children := make([]*entity.Execution, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		_, executionID, ok := parseIndexSuffix(suffix)
+		if !ok {
+			continue
+		}
+		execution, err := r.GetByID(ctx, executionID)
+		if err != nil {
+			continue
+		}
+		children = append(children, execution)
+	}
+	return children, nil
+}
+
+func (r *NatsExecutionRepository) Update(ctx context.Context, execution *entity.Execution) error {
+	if err := r.put(execution); err != nil {
+		return err
+	}
+	// Keep the status in the index values current for filtered listings
+	return r.putIndexEntries(execution)
+}
+
+func (r *NatsExecutionRepository) Walk(ctx context.Context, fn func(execution *entity.Execution) error) error {
+	keys, err := r.kv.Keys()
+	if err != nil {
+		if errors.Is(err, natspkg.ErrNoKeysFound) {
+			return nil
+		}
+		return err
+	}
+
 	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		entry, err := r.kv.Get(key)
 		if err != nil {
 			continue
@@ -59,49 +271,262 @@ func (r *NatsExecutionRepository) ListByUserID(ctx context.Context, userID strin
 			continue
 		}
 
-		if execution.UserID == userID {
-			executions = append(executions, &execution)
+		if err := fn(&execution); err != nil {
+			return err
 		}
 	}
 
-	return executions, nil
+	return nil
 }
 
-func (r *NatsExecutionRepository) Update(ctx context.Context, execution *entity.Execution) error {
-	return r.Save(ctx, execution)
+func (r *NatsExecutionRepository) Delete(ctx context.Context, id string) error {
+	execution, err := r.GetByID(ctx, id)
+	if err == nil {
+		for _, key := range indexKeys(execution) {
+			if err := r.index.Delete(key.key); err != nil {
+				log.Printf("Error deleting index key %s: %v", key, err)
+			}
+		}
+	}
+	return r.kv.Delete(id)
 }
 
-func (r *NatsExecutionRepository) GetActiveExecutionCount(ctx context.Context, userID string) (int, error) {
-	kv := r.kv
+func (r *NatsExecutionRepository) Stats(ctx context.Context) (*entity.BucketStats, error) {
+	status, err := r.kv.Status()
+	if err != nil {
+		return nil, err
+	}
+	return &entity.BucketStats{
+		Values: status.Values(),
+		Bytes:  status.Bytes(),
+	}, nil
+}
 
-	// Get all executions
-	entries, err := kv.Keys()
+// EnsureIndex backfills the indexes when the index bucket is still empty,
+// e.g. the first time the API starts with existing executions
+func (r *NatsExecutionRepository) EnsureIndex(ctx context.Context) error {
+	status, err := r.index.Status()
 	if err != nil {
-		if err.Error() == "nats: no keys found" {
-			return 0, nil // Return 0 if there are no executions
+		return err
+	}
+	if status.Values() > 0 {
+		return nil
+	}
+
+	indexed := 0
+	err = r.Walk(ctx, func(execution *entity.Execution) error {
+		if err := r.addToIndex(execution); err != nil {
+			return err
 		}
-		return 0, err
+		indexed++
+		return nil
+	})
+	if indexed > 0 {
+		log.Printf("Indexed %d existing executions", indexed)
 	}
+	return err
+}
 
-	count := 0
-	for _, entry := range entries {
-		// Get execution
-		data, err := kv.Get(entry)
+// addToIndex indexes a new execution, listing its day under each owner first
+func (r *NatsExecutionRepository) addToIndex(execution *entity.Execution) error {
+	day := indexDay(execution.CreatedAt)
+	for _, key := range indexKeys(execution) {
+		if err := r.addIndexDay(key.index, key.owner, day); err != nil {
+			return err
+		}
+	}
+	return r.putIndexEntries(execution)
+}
+
+func (r *NatsExecutionRepository) putIndexEntries(execution *entity.Execution) error {
+	return PutIndexEntries(r.index, execution)
+}
+
+// PutIndexEntries stores the status of the execution in its index entries.
+// Every writer of an execution status calls it, so filtered listings can rely
+// on the entries.
+func PutIndexEntries(index nats.KeyValue, execution *entity.Execution) error {
+	data, err := json.Marshal(&indexEntry{
+		UserID:       execution.UserID,
+		Status:       execution.Status,
+		FailureClass: execution.FailureClass,
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range indexKeys(execution) {
+		if _, err := index.Put(key.key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexDays returns the days with entries of an owner, oldest first, and the
+// revision of the list
+func (r *NatsExecutionRepository) indexDays(index, owner string) ([]int64, uint64, error) {
+	entry, err := r.index.Get(indexDaysKey(index, owner))
+	if err != nil {
+		if errors.Is(err, natspkg.ErrKeyNotFound) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	var days []int64
+	if err := json.Unmarshal(entry.Value(), &days); err != nil {
+		return nil, 0, err
+	}
+	return days, entry.Revision(), nil
+}
+
+func (r *NatsExecutionRepository) addIndexDay(index, owner string, day int64) error {
+	for attempt := 0; attempt < indexDayAttempts; attempt++ {
+		days, revision, err := r.indexDays(index, owner)
 		if err != nil {
-			continue
+			return err
 		}
+		i := sort.Search(len(days), func(i int) bool { return days[i] >= day })
+		if i < len(days) && days[i] == day {
+			return nil
+		}
+		days = append(days[:i], append([]int64{day}, days[i:]...)...)
 
-		var execution entity.Execution
-		if err := json.Unmarshal(data.Value(), &execution); err != nil {
-			continue
+		err = r.putIndexDays(index, owner, days, revision)
+		if !errors.Is(err, natspkg.ErrKeyExists) {
+			return err
 		}
+	}
+	return fmt.Errorf("too much contention on the days of %s %s", index, owner)
+}
 
-		// Count if it belongs to the user and is active
-		if execution.UserID == userID &&
-			(execution.Status == entity.StatusPending || execution.Status == entity.StatusRunning) {
-			count++
+// pruneIndexDays drops past days whose entries were all deleted. It is best
+// effort: a concurrent change keeps the days until the next listing.
+func (r *NatsExecutionRepository) pruneIndexDays(index, owner string, empty []int64) {
+	if len(empty) == 0 {
+		return
+	}
+	days, revision, err := r.indexDays(index, owner)
+	if err != nil || revision == 0 {
+		return
+	}
+
+	kept := days[:0]
+	for _, day := range days {
+		if !slices.Contains(empty, day) {
+			kept = append(kept, day)
 		}
 	}
+	if err := r.putIndexDays(index, owner, kept, revision); err != nil && !errors.Is(err, natspkg.ErrKeyExists) {
+		log.Printf("Error pruning index days of %s %s: %v", index, owner, err)
+	}
+}
 
-	return count, nil
+func (r *NatsExecutionRepository) putIndexDays(index, owner string, days []int64, revision uint64) error {
+	data, err := json.Marshal(days)
+	if err != nil {
+		return err
+	}
+	if revision == 0 {
+		_, err = r.index.Create(indexDaysKey(index, owner), data)
+	} else {
+		_, err = r.index.Update(indexDaysKey(index, owner), data, revision)
+	}
+	return err
+}
+
+// indexEntries returns the entries under prefix by their
+// "<inverted_created_at>.<execution_id>" suffix
+func (r *NatsExecutionRepository) indexEntries(ctx context.Context, prefix string) (map[string]indexEntry, error) {
+	watcher, err := r.index.Watch(prefix+">", natspkg.IgnoreDeletes())
+	if err != nil {
+		return nil, err
+	}
+	defer watcher.Stop()
+
+	entries := make(map[string]indexEntry)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case update := <-watcher.Updates():
+			// A nil entry marks the end of the initial values
+			if update == nil {
+				return entries, nil
+			}
+			var entry indexEntry
+			if err := json.Unmarshal(update.Value(), &entry); err != nil {
+				continue
+			}
+			entries[strings.TrimPrefix(update.Key(), prefix)] = entry
+		}
+	}
+}
+
+func indexPrefix(index, id string) string {
+	return fmt.Sprintf("%s.%s.", index, id)
+}
+
+// indexKey is an index key of an execution and the owner it is listed under
+type indexKey struct {
+	index string
+	owner string
+	key   string
+}
+
+func indexKeys(execution *entity.Execution) []indexKey {
+	day := indexDay(execution.CreatedAt)
+	suffix := fmt.Sprintf("%019d.%s", math.MaxInt64-execution.CreatedAt.UnixNano(), execution.ID)
+	owners := [][2]string{
+		{userIndex, execution.UserID},
+		{functionIndex, execution.FunctionID},
+	}
+	if execution.ParentExecutionID != "" {
+		owners = append(owners, [2]string{childrenIndex, execution.ParentExecutionID})
+	}
+
+	keys := make([]indexKey, 0, len(owners))
+	for _, owner := range owners {
+		keys = append(keys, indexKey{
+			index: owner[0],
+			owner: owner[1],
+			key:   indexDayPrefix(owner[0], owner[1], day) + suffix,
+		})
+	}
+	return keys
+}
+
+func indexDay(t time.Time) int64 {
+	return t.Unix() / 86400
+}
+
+func indexDayPrefix(index, owner string, day int64) string {
+	return fmt.Sprintf("%s%d.", indexPrefix(index, owner), day)
+}
+
+func indexDaysKey(index, owner string) string {
+	return fmt.Sprintf("days.%s.%s", index, owner)
+}
+
+func parseIndexSuffix(suffix string) (time.Time, string, bool) {
+	inverted, executionID, found := strings.Cut(suffix, ".")
+	if !found {
+		return time.Time{}, "", false
+	}
+	value, err := strconv.ParseInt(inverted, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.Unix(0, math.MaxInt64-value), executionID, true
+}
+
+func encodeCursor(suffix string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(suffix))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", appErrors.NewAppError("invalid_cursor", "Invalid cursor")
+	}
+	return string(data), nil
 }
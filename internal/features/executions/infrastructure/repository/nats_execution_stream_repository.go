@@ -19,6 +19,6 @@ func (r *NatsExecutionStreamRepository) PublishPending(execution *entity.Executi
 	if err != nil {
 		return err
 	}
-	_, err = r.js.Publish("executions.pending", data)
+	_, err = r.js.Publish(nats.PendingSubject(string(execution.Priority)), data)
 	return err
 }
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+// Times Acquire retries when the lease changes under it
+const acquireAttempts = 5
+
+// NatsLeaseRepository keeps one lease per running execution, keyed by
+// execution ID. Every change is compare-and-set, so a worker never extends or
+// releases a lease the reaper has already taken away.
+type NatsLeaseRepository struct {
+	kv nats.KeyValue
+}
+
+func NewNatsLeaseRepository(js nats.JetStreamContext) (*NatsLeaseRepository, error) {
+	kv, err := js.KeyValue(nats.LEASES_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsLeaseRepository{kv: nats.NewKeyValueAdapter(kv)}, nil
+}
+
+func (r *NatsLeaseRepository) Acquire(ctx context.Context, lease *entity.Lease) error {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < acquireAttempts; i++ {
+		current, revision, err := r.get(lease.ExecutionID)
+		if errors.Is(err, natspkg.ErrKeyNotFound) {
+			_, err = r.kv.Create(lease.ExecutionID, data)
+		} else if err == nil {
+			// Leases of earlier attempts, or being recovered, are replaced
+			if current.Attempt >= lease.Attempt && !current.Recovering && !sameHolder(current, lease) {
+				return entity.ErrLeaseLost
+			}
+			_, err = r.kv.Update(lease.ExecutionID, data, revision)
+		}
+		if errors.Is(err, natspkg.ErrKeyExists) {
+			continue
+		}
+		return err
+	}
+	return entity.ErrLeaseLost
+}
+
+func (r *NatsLeaseRepository) Renew(ctx context.Context, lease *entity.Lease) error {
+	current, revision, err := r.get(lease.ExecutionID)
+	if errors.Is(err, natspkg.ErrKeyNotFound) {
+		return entity.ErrLeaseLost
+	}
+	if err != nil {
+		return err
+	}
+	if !sameHolder(current, lease) {
+		return entity.ErrLeaseLost
+	}
+
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+	if _, err := r.kv.Update(lease.ExecutionID, data, revision); err != nil {
+		if errors.Is(err, natspkg.ErrKeyExists) {
+			return entity.ErrLeaseLost
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *NatsLeaseRepository) Revoke(ctx context.Context, lease *entity.Lease, recoverBy time.Time) (*entity.Lease, error) {
+	current, revision, err := r.get(lease.ExecutionID)
+	if errors.Is(err, natspkg.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Renewed since it was listed
+	if !sameHolder(current, lease) || !current.ExpiresAt.Equal(lease.ExpiresAt) {
+		return nil, nil
+	}
+
+	recovering := *current
+	recovering.Recovering = true
+	recovering.ExpiresAt = recoverBy
+	data, err := json.Marshal(&recovering)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.kv.Update(lease.ExecutionID, data, revision); err != nil {
+		if errors.Is(err, natspkg.ErrKeyExists) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &recovering, nil
+}
+
+func (r *NatsLeaseRepository) Release(ctx context.Context, lease *entity.Lease) (bool, error) {
+	current, revision, err := r.get(lease.ExecutionID)
+	if errors.Is(err, natspkg.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !sameHolder(current, lease) {
+		return false, nil
+	}
+
+	if err := r.kv.Delete(lease.ExecutionID, natspkg.LastRevision(revision)); err != nil {
+		if errors.Is(err, natspkg.ErrKeyExists) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *NatsLeaseRepository) List(ctx context.Context) ([]*entity.Lease, error) {
+	keys, err := r.kv.Keys()
+	if errors.Is(err, natspkg.ErrNoKeysFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]*entity.Lease, 0, len(keys))
+	for _, key := range keys {
+		lease, _, err := r.get(key)
+		if errors.Is(err, natspkg.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, lease)
+	}
+	return leases, nil
+}
+
+func (r *NatsLeaseRepository) get(executionID string) (*entity.Lease, uint64, error) {
+	entry, err := r.kv.Get(executionID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var lease entity.Lease
+	if err := json.Unmarshal(entry.Value(), &lease); err != nil {
+		return nil, 0, err
+	}
+	return &lease, entry.Revision(), nil
+}
+
+// sameHolder tells whether two leases are held by the same party: the worker
+// running an attempt, or the reaper recovering it
+func sameHolder(a, b *entity.Lease) bool {
+	return a.WorkerID == b.WorkerID && a.Attempt == b.Attempt && a.Recovering == b.Recovering
+}
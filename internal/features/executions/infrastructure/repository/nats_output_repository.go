@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"io"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+type NatsOutputRepository struct {
+	store natspkg.ObjectStore
+}
+
+func NewNatsOutputRepository(js nats.JetStreamContext) (*NatsOutputRepository, error) {
+	store, err := js.ObjectStore(nats.OUTPUTS_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsOutputRepository{store: store}, nil
+}
+
+func (r *NatsOutputRepository) Put(ctx context.Context, executionID string, output []byte) (*entity.OutputRef, error) {
+	sum := sha256.Sum256(output)
+	_, err := r.store.Put(&natspkg.ObjectMeta{
+		Name:        executionID,
+		Description: "Output of execution " + executionID,
+	}, bytes.NewReader(output), natspkg.Context(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.OutputRef{
+		Size:   int64(len(output)),
+		SHA256: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+func (r *NatsOutputRepository) Get(ctx context.Context, executionID string) (io.ReadCloser, error) {
+	return r.store.Get(executionID, natspkg.Context(ctx))
+}
+
+func (r *NatsOutputRepository) Delete(ctx context.Context, executionID string) error {
+	return r.store.Delete(executionID)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"fmt"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+// Attempts of a compare-and-set update before giving up under contention
+const quotaMaxAttempts = 20
+
+// quotaSlots is the value of a counter: the executions holding a slot
+type quotaSlots struct {
+	Slots map[string]time.Time `json:"slots"`
+}
+
+// NatsQuotaRepository keeps concurrency counters in KV. Every change is a
+// compare-and-set on the key revision, so concurrent API replicas and
+// workers never lose an update.
+type NatsQuotaRepository struct {
+	kv nats.KeyValue
+}
+
+func NewNatsQuotaRepository(js nats.JetStreamContext) (*NatsQuotaRepository, error) {
+	kv, err := js.KeyValue(nats.QUOTAS_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsQuotaRepository{kv: nats.NewKeyValueAdapter(kv)}, nil
+}
+
+func (r *NatsQuotaRepository) Reserve(ctx context.Context, key string, executionID string, limit int) (bool, error) {
+	reserved := false
+	err := r.update(ctx, key, func(slots *quotaSlots) bool {
+		if _, ok := slots.Slots[executionID]; ok {
+			reserved = true
+			return false
+		}
+		if len(slots.Slots) >= limit {
+			reserved = false
+			return false
+		}
+		slots.Slots[executionID] = time.Now()
+		reserved = true
+		return true
+	})
+	return reserved, err
+}
+
+func (r *NatsQuotaRepository) Release(ctx context.Context, key string, executionID string) error {
+	return r.update(ctx, key, func(slots *quotaSlots) bool {
+		if _, ok := slots.Slots[executionID]; !ok {
+			return false
+		}
+		delete(slots.Slots, executionID)
+		return true
+	})
+}
+
+func (r *NatsQuotaRepository) Slots(ctx context.Context) ([]*entity.QuotaSlot, error) {
+	keys, err := r.kv.Keys()
+	if errors.Is(err, natspkg.ErrNoKeysFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var taken []*entity.QuotaSlot
+	for _, key := range keys {
+		entry, err := r.kv.Get(key)
+		if errors.Is(err, natspkg.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var slots quotaSlots
+		if err := json.Unmarshal(entry.Value(), &slots); err != nil {
+			return nil, err
+		}
+		for executionID, reservedAt := range slots.Slots {
+			taken = append(taken, &entity.QuotaSlot{Key: key, ExecutionID: executionID, ReservedAt: reservedAt})
+		}
+	}
+	return taken, nil
+}
+
+// update applies change to the counter and writes it back if change returns
+// true, retrying when another writer got there first
+func (r *NatsQuotaRepository) update(ctx context.Context, key string, change func(slots *quotaSlots) bool) error {
+	for attempt := 0; attempt < quotaMaxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		slots := &quotaSlots{Slots: map[string]time.Time{}}
+		var revision uint64
+		entry, err := r.kv.Get(key)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(entry.Value(), slots); err != nil {
+				return err
+			}
+			if slots.Slots == nil {
+				slots.Slots = map[string]time.Time{}
+			}
+			revision = entry.Revision()
+		case !errors.Is(err, natspkg.ErrKeyNotFound):
+			return err
+		}
+
+		if !change(slots) {
+			return nil
+		}
+
+		data, err := json.Marshal(slots)
+		if err != nil {
+			return err
+		}
+		if revision == 0 {
+			_, err = r.kv.Create(key, data)
+		} else {
+			_, err = r.kv.Update(key, data, revision)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, natspkg.ErrKeyExists) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("quota %s: too much contention", key)
+}
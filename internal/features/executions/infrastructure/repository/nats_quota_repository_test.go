@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"faas/internal/shared/infrastructure/nats"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+type fakeEntry struct {
+	value    []byte
+	revision uint64
+}
+
+func (e *fakeEntry) Value() []byte    { return e.value }
+func (e *fakeEntry) Revision() uint64 { return e.revision }
+
+// fakeKV keeps entries in memory with revisions like a KV bucket. interleave
+// runs once right before the next write, standing in for another writer.
+type fakeKV struct {
+	nats.KeyValue
+	entries    map[string]*fakeEntry
+	revision   uint64
+	interleave func()
+	writes     int
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{entries: map[string]*fakeEntry{}}
+}
+
+func (kv *fakeKV) Get(key string) (nats.KeyValueEntry, error) {
+	entry, ok := kv.entries[key]
+	if !ok {
+		return nil, natspkg.ErrKeyNotFound
+	}
+	return entry, nil
+}
+
+func (kv *fakeKV) Put(key string, value []byte) (uint64, error) {
+	kv.revision++
+	kv.entries[key] = &fakeEntry{value: value, revision: kv.revision}
+	return kv.revision, nil
+}
+
+func (kv *fakeKV) Create(key string, value []byte) (uint64, error) {
+	kv.beforeWrite()
+	if _, ok := kv.entries[key]; ok {
+		return 0, natspkg.ErrKeyExists
+	}
+	return kv.Put(key, value)
+}
+
+func (kv *fakeKV) Update(key string, value []byte, last uint64) (uint64, error) {
+	kv.beforeWrite()
+	if entry, ok := kv.entries[key]; !ok || entry.revision != last {
+		return 0, natspkg.ErrKeyExists
+	}
+	return kv.Put(key, value)
+}
+
+func (kv *fakeKV) Keys() ([]string, error) {
+	if len(kv.entries) == 0 {
+		return nil, natspkg.ErrNoKeysFound
+	}
+	keys := make([]string, 0, len(kv.entries))
+	for key := range kv.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (kv *fakeKV) beforeWrite() {
+	kv.writes++
+	if interleave := kv.interleave; interleave != nil {
+		kv.interleave = nil
+		interleave()
+	}
+}
+
+// holders returns the executions holding a slot of key
+func (kv *fakeKV) holders(t *testing.T, key string) []string {
+	entry, ok := kv.entries[key]
+	if !ok {
+		return nil
+	}
+	var slots quotaSlots
+	if err := json.Unmarshal(entry.value, &slots); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for id := range slots.Slots {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestNatsQuotaRepositoryReserve(t *testing.T) {
+	const key = "user.u1"
+
+	tests := []struct {
+		name        string
+		held        []string
+		limit       int
+		concurrent  string // reserved by another writer between our read and write
+		execution   string
+		wantOK      bool
+		wantHolders []string
+	}{
+		{name: "free slot", limit: 2, execution: "e1", wantOK: true, wantHolders: []string{"e1"}},
+		{name: "limit reached", held: []string{"e1", "e2"}, limit: 2, execution: "e3", wantOK: false, wantHolders: []string{"e1", "e2"}},
+		{name: "already holding a slot", held: []string{"e1"}, limit: 1, execution: "e1", wantOK: true, wantHolders: []string{"e1"}},
+		{name: "concurrent reservation keeps both", held: []string{"e1"}, limit: 3, concurrent: "e2", execution: "e3", wantOK: true, wantHolders: []string{"e1", "e2", "e3"}},
+		{name: "concurrent reservation takes the last slot", held: []string{"e1"}, limit: 2, concurrent: "e2", execution: "e3", wantOK: false, wantHolders: []string{"e1", "e2"}},
+		{name: "concurrent first reservation", limit: 1, concurrent: "e2", execution: "e3", wantOK: false, wantHolders: []string{"e2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			kv := newFakeKV()
+			repo := &NatsQuotaRepository{kv: kv}
+			for _, id := range tt.held {
+				if ok, err := repo.Reserve(ctx, key, id, len(tt.held)); err != nil || !ok {
+					t.Fatalf("Reserve(%s) = %v, %v", id, ok, err)
+				}
+			}
+			if tt.concurrent != "" {
+				kv.interleave = func() {
+					if ok, err := repo.Reserve(ctx, key, tt.concurrent, tt.limit); err != nil || !ok {
+						t.Fatalf("concurrent Reserve(%s) = %v, %v", tt.concurrent, ok, err)
+					}
+				}
+			}
+
+			ok, err := repo.Reserve(ctx, key, tt.execution, tt.limit)
+			if err != nil {
+				t.Fatalf("Reserve() error = %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("Reserve() = %v, want %v", ok, tt.wantOK)
+			}
+			if got := kv.holders(t, key); !slices.Equal(got, tt.wantHolders) {
+				t.Errorf("holders = %v, want %v", got, tt.wantHolders)
+			}
+		})
+	}
+}
+
+func TestNatsQuotaRepositoryRelease(t *testing.T) {
+	const key = "user.u1"
+
+	tests := []struct {
+		name        string
+		held        []string
+		concurrent  string // reserved by another writer between our read and write
+		execution   string
+		wantWrites  int
+		wantHolders []string
+	}{
+		{name: "held slot", held: []string{"e1", "e2"}, execution: "e1", wantWrites: 1, wantHolders: []string{"e2"}},
+		{name: "slot not held", held: []string{"e1"}, execution: "e2", wantWrites: 0, wantHolders: []string{"e1"}},
+		{name: "no counter", execution: "e1", wantWrites: 0},
+		{name: "concurrent reservation is kept", held: []string{"e1"}, concurrent: "e2", execution: "e1", wantWrites: 3, wantHolders: []string{"e2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			kv := newFakeKV()
+			repo := &NatsQuotaRepository{kv: kv}
+			for _, id := range tt.held {
+				if ok, err := repo.Reserve(ctx, key, id, 10); err != nil || !ok {
+					t.Fatalf("Reserve(%s) = %v, %v", id, ok, err)
+				}
+			}
+			kv.writes = 0
+			if tt.concurrent != "" {
+				kv.interleave = func() {
+					if ok, err := repo.Reserve(ctx, key, tt.concurrent, 10); err != nil || !ok {
+						t.Fatalf("concurrent Reserve(%s) = %v, %v", tt.concurrent, ok, err)
+					}
+				}
+			}
+
+			if err := repo.Release(ctx, key, tt.execution); err != nil {
+				t.Fatalf("Release() error = %v", err)
+			}
+			if kv.writes != tt.wantWrites {
+				t.Errorf("writes = %d, want %d", kv.writes, tt.wantWrites)
+			}
+			if got := kv.holders(t, key); !slices.Equal(got, tt.wantHolders) {
+				t.Errorf("holders = %v, want %v", got, tt.wantHolders)
+			}
+		})
+	}
+}
+
+func TestNatsQuotaRepositorySlots(t *testing.T) {
+	ctx := context.Background()
+	kv := newFakeKV()
+	repo := &NatsQuotaRepository{kv: kv}
+
+	slots, err := repo.Slots(ctx)
+	if err != nil || len(slots) != 0 {
+		t.Fatalf("Slots() on an empty bucket = %v, %v", slots, err)
+	}
+
+	before := time.Now()
+	for _, reservation := range []struct{ key, id string }{{"user.u1", "e1"}, {"user.u1", "e2"}, {"function.f1", "e1"}} {
+		if _, err := repo.Reserve(ctx, reservation.key, reservation.id, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slots, err = repo.Slots(ctx)
+	if err != nil {
+		t.Fatalf("Slots() error = %v", err)
+	}
+	var got []string
+	for _, slot := range slots {
+		if slot.ReservedAt.Before(before) {
+			t.Errorf("slot %s/%s reserved at %v, before %v", slot.Key, slot.ExecutionID, slot.ReservedAt, before)
+		}
+		got = append(got, slot.Key+"/"+slot.ExecutionID)
+	}
+	sort.Strings(got)
+	want := []string{"function.f1/e1", "user.u1/e1", "user.u1/e2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Slots() = %v, want %v", got, want)
+	}
+}
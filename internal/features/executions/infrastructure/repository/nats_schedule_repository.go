@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+// scheduleRescanInterval is how often the whole schedule is listed to find
+// the oldest due minute, e.g. entries another replica wrote with a late clock
+const scheduleRescanInterval = 5 * time.Minute
+
+// NatsScheduleRepository keys the entries by the minute they are due,
+// "<minute>.<execution_id>", so each tick only reads the minutes from the
+// oldest one with entries up to now
+type NatsScheduleRepository struct {
+	kv nats.KeyValue
+
+	mu sync.Mutex
+	// oldest is the first minute that may still hold entries
+	oldest      int64
+	rescannedAt time.Time
+}
+
+func NewNatsScheduleRepository(js nats.JetStreamContext) (*NatsScheduleRepository, error) {
+	kv, err := js.KeyValue(nats.SCHEDULES_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsScheduleRepository{kv: nats.NewKeyValueAdapter(kv)}, nil
+}
+
+func (r *NatsScheduleRepository) Schedule(ctx context.Context, execution *entity.Execution) error {
+	data, err := json.Marshal(&entity.ScheduledExecution{
+		ExecutionID: execution.ID,
+		RunAt:       *execution.RunAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.kv.Put(scheduleKey(*execution.RunAt, execution.ID), data)
+	return err
+}
+
+func (r *NatsScheduleRepository) ListDue(ctx context.Context, now time.Time) ([]*entity.ScheduledExecution, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.rescannedAt) >= scheduleRescanInterval {
+		if err := r.rescan(ctx, now); err != nil {
+			return nil, err
+		}
+		r.rescannedAt = now
+	}
+
+	current := scheduleMinute(now)
+	var due []*entity.ScheduledExecution
+	for minute := r.oldest; minute <= current; minute++ {
+		entries, err := r.minuteEntries(ctx, minute)
+		if err != nil {
+			return nil, err
+		}
+		// Past minutes that are empty stay empty, as entries are scheduled ahead
+		if len(entries) == 0 && minute == r.oldest && minute < current {
+			r.oldest++
+		}
+
+		for _, scheduled := range entries {
+			if scheduled.RunAt.After(now) {
+				continue
+			}
+			if scheduled.ClaimedUntil != nil && scheduled.ClaimedUntil.After(now) {
+				continue
+			}
+			due = append(due, scheduled)
+		}
+	}
+
+	return due, nil
+}
+
+func (r *NatsScheduleRepository) Claim(ctx context.Context, scheduled *entity.ScheduledExecution, until time.Time) (bool, error) {
+	claimed := *scheduled
+	claimed.ClaimedUntil = &until
+	data, err := json.Marshal(&claimed)
+	if err != nil {
+		return false, err
+	}
+
+	revision, err := r.kv.Update(scheduled.Key, data, scheduled.Revision)
+	if err != nil {
+		if errors.Is(err, natspkg.ErrKeyExists) {
+			// Another dispatcher claimed it first
+			return false, nil
+		}
+		return false, err
+	}
+	scheduled.ClaimedUntil = &until
+	scheduled.Revision = revision
+	return true, nil
+}
+
+func (r *NatsScheduleRepository) Remove(ctx context.Context, scheduled *entity.ScheduledExecution) error {
+	err := r.kv.Delete(scheduled.Key, natspkg.LastRevision(scheduled.Revision))
+	if errors.Is(err, natspkg.ErrKeyExists) {
+		// The claim expired and another dispatcher took the entry over
+		return nil
+	}
+	return err
+}
+
+// minuteEntries returns the entries due within the given minute
+func (r *NatsScheduleRepository) minuteEntries(ctx context.Context, minute int64) ([]*entity.ScheduledExecution, error) {
+	watcher, err := r.kv.Watch(fmt.Sprintf("%012d.>", minute), natspkg.IgnoreDeletes())
+	if err != nil {
+		return nil, err
+	}
+	defer watcher.Stop()
+
+	var entries []*entity.ScheduledExecution
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case entry := <-watcher.Updates():
+			// A nil entry marks the end of the initial values
+			if entry == nil {
+				return entries, nil
+			}
+			var scheduled entity.ScheduledExecution
+			if err := json.Unmarshal(entry.Value(), &scheduled); err != nil {
+				continue
+			}
+			scheduled.Key = entry.Key()
+			scheduled.Revision = entry.Revision()
+			entries = append(entries, &scheduled)
+		}
+	}
+}
+
+// rescan lists every key to find the oldest minute with entries
+func (r *NatsScheduleRepository) rescan(ctx context.Context, now time.Time) error {
+	keys, err := r.kv.Keys()
+	if err != nil && !errors.Is(err, natspkg.ErrNoKeysFound) {
+		return err
+	}
+
+	oldest := scheduleMinute(now)
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		minute, ok := parseScheduleKey(key)
+		if ok && minute < oldest {
+			oldest = minute
+		}
+	}
+
+	r.oldest = oldest
+	return nil
+}
+
+func scheduleMinute(t time.Time) int64 {
+	return t.Unix() / 60
+}
+
+func scheduleKey(runAt time.Time, executionID string) string {
+	return fmt.Sprintf("%012d.%s", scheduleMinute(runAt), executionID)
+}
+
+func parseScheduleKey(key string) (int64, bool) {
+	minute, _, found := strings.Cut(key, ".")
+	if !found {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(minute, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
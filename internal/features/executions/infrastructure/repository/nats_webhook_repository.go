@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"log"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+const (
+	webhookSenders = "webhook-senders"
+
+	// A delivery in progress extends its ack deadline every half of it
+	webhookAckWait = time.Minute
+)
+
+type NatsWebhookRepository struct {
+	js nats.JetStreamContext
+	kv nats.KeyValue
+}
+
+func NewNatsWebhookRepository(js nats.JetStreamContext) (*NatsWebhookRepository, error) {
+	kv, err := js.KeyValue(nats.DELIVERIES_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsWebhookRepository{
+		js: js,
+		kv: nats.NewKeyValueAdapter(kv),
+	}, nil
+}
+
+func (r *NatsWebhookRepository) Create(ctx context.Context, delivery *entity.WebhookDelivery) (bool, error) {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return false, err
+	}
+	revision, err := r.kv.Create(delivery.ExecutionID, data)
+	if err != nil {
+		if errors.Is(err, natspkg.ErrKeyExists) {
+			return false, nil
+		}
+		return false, err
+	}
+	delivery.Revision = revision
+	return true, nil
+}
+
+func (r *NatsWebhookRepository) Update(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	revision, err := r.kv.Update(delivery.ExecutionID, data, delivery.Revision)
+	if err != nil {
+		return err
+	}
+	delivery.Revision = revision
+	return nil
+}
+
+func (r *NatsWebhookRepository) Get(ctx context.Context, executionID string) (*entity.WebhookDelivery, error) {
+	entry, err := r.kv.Get(executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery entity.WebhookDelivery
+	if err := json.Unmarshal(entry.Value(), &delivery); err != nil {
+		return nil, err
+	}
+	delivery.Revision = entry.Revision()
+	return &delivery, nil
+}
+
+func (r *NatsWebhookRepository) Consume(ctx context.Context, handler func(ctx context.Context, executionID string, received int) time.Duration) error {
+	sub, err := pullSubscribe(r.js, nats.WEBHOOKS_STREAM, &natspkg.ConsumerConfig{
+		Durable:       webhookSenders,
+		FilterSubject: nats.WEBHOOKS_SUBJECT,
+		AckPolicy:     natspkg.AckExplicitPolicy,
+		AckWait:       webhookAckWait,
+		MaxDeliver:    -1,
+		DeliverPolicy: natspkg.DeliverAllPolicy,
+	})
+	if err != nil {
+		return err
+	}
+
+	return pullLoop(ctx, sub, webhookAckWait/2, func(msg *natspkg.Msg) {
+		received := 1
+		if meta, err := msg.Metadata(); err == nil {
+			received = int(meta.NumDelivered)
+		}
+
+		if retry := handler(ctx, string(msg.Data), received); retry > 0 {
+			if err := msg.NakWithDelay(retry); err != nil {
+				log.Printf("Error scheduling retry of webhook %s: %v", string(msg.Data), err)
+			}
+			return
+		}
+		msg.Ack()
+	})
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/nats"
+	"sort"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+// NatsWorkerRepository stores the last heartbeat of every worker. The bucket
+// TTL drops workers that stop beating.
+type NatsWorkerRepository struct {
+	kv nats.KeyValue
+}
+
+func NewNatsWorkerRepository(js nats.JetStreamContext) (*NatsWorkerRepository, error) {
+	kv, err := js.KeyValue(nats.WORKERS_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsWorkerRepository{kv: nats.NewKeyValueAdapter(kv)}, nil
+}
+
+func (r *NatsWorkerRepository) Heartbeat(ctx context.Context, worker *entity.Worker) error {
+	data, err := json.Marshal(worker)
+	if err != nil {
+		return err
+	}
+	_, err = r.kv.Put(worker.ID, data)
+	return err
+}
+
+func (r *NatsWorkerRepository) Deregister(ctx context.Context, workerID string) error {
+	err := r.kv.Delete(workerID)
+	if errors.Is(err, natspkg.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *NatsWorkerRepository) List(ctx context.Context) ([]*entity.Worker, error) {
+	keys, err := r.kv.Keys()
+	if errors.Is(err, natspkg.ErrNoKeysFound) {
+		return []*entity.Worker{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	workers := make([]*entity.Worker, 0, len(keys))
+	for _, key := range keys {
+		entry, err := r.kv.Get(key)
+		if errors.Is(err, natspkg.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var worker entity.Worker
+		if err := json.Unmarshal(entry.Value(), &worker); err != nil {
+			return nil, err
+		}
+		workers = append(workers, &worker)
+	}
+
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
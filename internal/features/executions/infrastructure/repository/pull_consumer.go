@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"faas/internal/shared/infrastructure/nats"
+	"log"
+	"sync"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+const (
+	// How long a single fetch waits for messages
+	pullWait = time.Second
+	// Messages handled at once, so a slow one doesn't hold up the others
+	pullConcurrency = 16
+)
+
+// pullSubscribe binds to a durable pull consumer, creating it first so that
+// unsubscribing never deletes it for the other replicas
+func pullSubscribe(js nats.JetStreamContext, stream string, cfg *natspkg.ConsumerConfig) (*natspkg.Subscription, error) {
+	if _, err := js.AddConsumer(stream, cfg); err != nil {
+		if _, err := js.UpdateConsumer(stream, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return js.PullSubscribe(cfg.FilterSubject, cfg.Durable, natspkg.Bind(stream, cfg.Durable))
+}
+
+// pullLoop hands fetched messages to handle, each in its own goroutine, until
+// ctx is done. Only as many messages as there are free slots are fetched, and
+// a message being handled is marked in progress every progressEvery so it
+// isn't redelivered meanwhile.
+func pullLoop(ctx context.Context, sub *natspkg.Subscription, progressEvery time.Duration, handle func(msg *natspkg.Msg)) error {
+	defer sub.Unsubscribe()
+
+	slots := make(chan struct{}, pullConcurrency)
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	for ctx.Err() == nil {
+		// Wait for a free slot, then take the others that are free too
+		select {
+		case <-ctx.Done():
+			return nil
+		case slots <- struct{}{}:
+		}
+		free := 1
+	take:
+		for free < pullConcurrency {
+			select {
+			case slots <- struct{}{}:
+				free++
+			default:
+				break take
+			}
+		}
+
+		msgs, err := sub.Fetch(free, natspkg.MaxWait(pullWait))
+		if err != nil && !errors.Is(err, natspkg.ErrTimeout) && !errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Error fetching from %s: %v", sub.Subject, err)
+			time.Sleep(pullWait)
+		}
+		for _, msg := range msgs {
+			free--
+			inFlight.Add(1)
+			go func(msg *natspkg.Msg) {
+				defer func() {
+					<-slots
+					inFlight.Done()
+				}()
+				handleInProgress(msg, progressEvery, handle)
+			}(msg)
+		}
+
+		// Give back the slots nothing was fetched for
+		for ; free > 0; free-- {
+			<-slots
+		}
+	}
+
+	return nil
+}
+
+// handleInProgress runs handle while extending the ack deadline of msg
+func handleInProgress(msg *natspkg.Msg, progressEvery time.Duration, handle func(msg *natspkg.Msg)) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(progressEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					log.Printf("Error extending the ack deadline on %s: %v", msg.Subject, err)
+				}
+			}
+		}
+	}()
+
+	handle(msg)
+}
@@ -3,7 +3,11 @@ package http
 import (
 	"faas/internal/features/executions/application/dto"
 	"faas/internal/features/executions/application/service"
+	"faas/internal/shared/domain/errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,9 +33,37 @@ func (h *ExecutionHandler) CreateExecution(c *gin.Context) {
 		return
 	}
 
-	execution, err := h.executionService.CreateExecution(c.Request.Context(), &req, userID)
+	execution, err := h.executionService.CreateExecution(c.Request.Context(), &req, userID, c.GetHeader("X-Parent-Execution-ID"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Code(err) == "quota_exceeded" {
+			c.Header("Retry-After", strconv.Itoa(int(service.QuotaRetryAfter.Seconds())))
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, execution)
+}
+
+func (h *ExecutionHandler) RerunExecution(c *gin.Context) {
+	var req dto.RerunExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	execution, err := h.executionService.RerunExecution(c.Request.Context(), c.Param("id"), &req, userID)
+	if err != nil {
+		if errors.Code(err) == "quota_exceeded" {
+			c.Header("Retry-After", strconv.Itoa(int(service.QuotaRetryAfter.Seconds())))
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -46,7 +78,7 @@ func (h *ExecutionHandler) GetExecution(c *gin.Context) {
 		return
 	}
 
-	execution, err := h.executionService.GetExecution(c.Request.Context(), executionID, userID)
+	execution, err := h.executionService.GetExecution(c.Request.Context(), executionID, userID, c.GetHeader("X-Parent-Execution-ID"))
 	if err != nil {
 		if err.Error() == "unauthorized" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "not authorized to access this execution"})
@@ -59,6 +91,23 @@ func (h *ExecutionHandler) GetExecution(c *gin.Context) {
 	c.JSON(http.StatusOK, execution)
 }
 
+// GetExecutionTree returns the call tree the execution belongs to
+func (h *ExecutionHandler) GetExecutionTree(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tree, err := h.executionService.GetExecutionTree(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
 func (h *ExecutionHandler) ListExecutions(c *gin.Context) {
 	userID := c.GetHeader("X-User-ID")
 	if userID == "" {
@@ -66,11 +115,35 @@ func (h *ExecutionHandler) ListExecutions(c *gin.Context) {
 		return
 	}
 
-	executions, err := h.executionService.ListUserExecutions(c.Request.Context(), userID)
+	var req dto.ListExecutionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	executions, err := h.executionService.ListUserExecutions(c.Request.Context(), &req, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, executions)
 }
+
+// errorStatus maps service errors to HTTP status codes
+func errorStatus(err error) int {
+	code := errors.Code(err)
+	switch {
+	case strings.HasPrefix(code, "invalid_"):
+		return http.StatusBadRequest
+	case code == "unauthorized":
+		return http.StatusForbidden
+	case strings.HasSuffix(code, "_not_found"):
+		return http.StatusNotFound
+	case code == "quota_exceeded":
+		return http.StatusTooManyRequests
+	case code == "call_depth_exceeded":
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
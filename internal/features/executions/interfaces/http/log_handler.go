@@ -0,0 +1,85 @@
+package http
+
+import (
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/application/service"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Interval of the keep-alive events sent while a stream is idle
+const sseKeepAlive = 15 * time.Second
+
+type LogHandler struct {
+	logService *service.LogService
+}
+
+func NewLogHandler(service *service.LogService) *LogHandler {
+	return &LogHandler{logService: service}
+}
+
+func (h *LogHandler) GetLogs(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dto.GetLogsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs, err := h.logService.GetLogs(c.Request.Context(), c.Param("id"), userID, &req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
+
+// StreamLogs relays the execution logs as server-sent events: a "log" event
+// per line, starting from the first one, and an "end" event once the
+// execution has finished
+func (h *LogHandler) StreamLogs(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	entries, err := h.logService.StreamLogs(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				c.SSEvent("end", gin.H{"execution_id": id})
+				return false
+			}
+			c.SSEvent("log", entry)
+			return true
+		case <-keepAlive.C:
+			c.SSEvent("ping", "")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
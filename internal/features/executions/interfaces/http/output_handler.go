@@ -0,0 +1,38 @@
+package http
+
+import (
+	"faas/internal/features/executions/application/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OutputHandler struct {
+	outputService *service.OutputService
+}
+
+func NewOutputHandler(service *service.OutputService) *OutputHandler {
+	return &OutputHandler{outputService: service}
+}
+
+// GetOutput returns the raw output of the execution, inline or offloaded
+func (h *OutputHandler) GetOutput(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	output, err := h.outputService.GetOutput(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer output.Reader.Close()
+
+	headers := map[string]string{}
+	if output.SHA256 != "" {
+		headers["X-Faas-Output-Sha256"] = output.SHA256
+	}
+	c.DataFromReader(http.StatusOK, output.Size, "application/octet-stream", output.Reader, headers)
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"faas/internal/features/executions/application/service"
+	"faas/internal/shared/domain/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+}
+
+func NewRetentionHandler(service *service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{retentionService: service}
+}
+
+func (h *RetentionHandler) GetStatus(c *gin.Context) {
+	status, err := h.retentionService.GetStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *RetentionHandler) Purge(c *gin.Context) {
+	stats, err := h.retentionService.Purge(c.Request.Context())
+	if err != nil {
+		if errors.Code(err) == "purge_in_progress" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
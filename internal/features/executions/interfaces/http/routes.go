@@ -1,17 +1,90 @@
 package http
 
 import (
+	"faas/internal/shared/infrastructure/auth"
 	"faas/internal/shared/infrastructure/http/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupExecutionRoutes(r *gin.Engine, handler *ExecutionHandler, jwtSecret string) {
+func SetupExecutionRoutes(r *gin.Engine, handler *ExecutionHandler, jwtSecret, invokeTokenSecret string) {
+	// Running executions can invoke functions and follow what they started
+	invoke := r.Group("/api/executions")
+	invoke.Use(middleware.ExtractUserID(jwtSecret, middleware.ScopedSecret{Scope: auth.ScopeInvoke, Secret: invokeTokenSecret}))
+	{
+		invoke.POST("", handler.CreateExecution)
+		invoke.GET("/:id", handler.GetExecution)
+	}
+
 	executions := r.Group("/api/executions")
 	executions.Use(middleware.ExtractUserID(jwtSecret))
 	{
-		executions.POST("", handler.CreateExecution)
-		executions.GET("/:id", handler.GetExecution)
+		executions.POST("/:id/rerun", handler.RerunExecution)
+		executions.GET("/:id/tree", handler.GetExecutionTree)
 		executions.GET("", handler.ListExecutions)
 	}
 }
+
+func SetupRetentionRoutes(r *gin.Engine, handler *RetentionHandler, jwtSecret string) {
+	admin := r.Group("/api/admin/executions")
+	admin.Use(middleware.ExtractUserID(jwtSecret), middleware.RequireRole("admin"))
+	{
+		admin.GET("/retention", handler.GetStatus)
+		admin.POST("/retention/purge", handler.Purge)
+	}
+}
+
+func SetupLogRoutes(r *gin.Engine, handler *LogHandler, jwtSecret string) {
+	executions := r.Group("/api/executions")
+	executions.Use(middleware.ExtractUserID(jwtSecret))
+	{
+		executions.GET("/:id/logs", handler.GetLogs)
+		executions.GET("/:id/logs/stream", handler.StreamLogs)
+	}
+}
+
+func SetupEventRoutes(r *gin.Engine, handler *EventHandler, jwtSecret string) {
+	executions := r.Group("/api/executions")
+	executions.Use(middleware.ExtractUserID(jwtSecret))
+	{
+		executions.GET("/events", handler.StreamEvents)
+	}
+}
+
+func SetupWebhookRoutes(r *gin.Engine, handler *WebhookHandler, jwtSecret string) {
+	executions := r.Group("/api/executions")
+	executions.Use(middleware.ExtractUserID(jwtSecret))
+	{
+		executions.GET("/:id/deliveries", handler.GetDeliveries)
+	}
+}
+
+func SetupOutputRoutes(r *gin.Engine, handler *OutputHandler, jwtSecret string) {
+	executions := r.Group("/api/executions")
+	executions.Use(middleware.ExtractUserID(jwtSecret))
+	{
+		executions.GET("/:id/output", handler.GetOutput)
+	}
+}
+
+func SetupWorkerRoutes(r *gin.Engine, handler *WorkerHandler, jwtSecret string) {
+	admin := r.Group("/api/admin/workers")
+	admin.Use(middleware.ExtractUserID(jwtSecret), middleware.RequireRole("admin"))
+	{
+		admin.GET("", handler.ListWorkers)
+	}
+}
+
+func SetupUsageRoutes(r *gin.Engine, handler *UsageHandler, jwtSecret string) {
+	executions := r.Group("/api/executions")
+	executions.Use(middleware.ExtractUserID(jwtSecret))
+	{
+		executions.GET("/usage", handler.GetUserUsage)
+	}
+
+	admin := r.Group("/api/admin/usage")
+	admin.Use(middleware.ExtractUserID(jwtSecret), middleware.RequireRole("admin"))
+	{
+		admin.GET("", handler.GetUsage)
+	}
+}
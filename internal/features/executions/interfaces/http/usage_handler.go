@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/csv"
+	"faas/internal/features/executions/application/dto"
+	"faas/internal/features/executions/application/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+func NewUsageHandler(service *service.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: service}
+}
+
+// GetUserUsage returns the caller's usage by function
+func (h *UsageHandler) GetUserUsage(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dto.UsageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	usage, err := h.usageService.GetUserUsage(c.Request.Context(), &req, userID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	writeUsage(c, &req, usage)
+}
+
+// GetUsage returns the usage of every user, for admins
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	var req dto.UsageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	usage, err := h.usageService.GetUsage(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	writeUsage(c, &req, usage)
+}
+
+func writeUsage(c *gin.Context, req *dto.UsageRequest, usage *dto.UsageResponse) {
+	if req.Format != "csv" {
+		c.JSON(http.StatusOK, usage)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="usage.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		usage.GroupBy + "_id", "executions", "cpu_seconds", "peak_memory_bytes",
+		"network_rx_bytes", "network_tx_bytes", "block_read_bytes", "block_write_bytes", "wall_time_ms",
+	})
+	for _, row := range usage.Rows {
+		key := row.UserID
+		if usage.GroupBy == "function" {
+			key = row.FunctionID
+		}
+		w.Write([]string{
+			key,
+			strconv.Itoa(row.Executions),
+			strconv.FormatFloat(row.CPUSeconds, 'f', 3, 64),
+			strconv.FormatUint(row.PeakMemoryBytes, 10),
+			strconv.FormatUint(row.NetworkRxBytes, 10),
+			strconv.FormatUint(row.NetworkTxBytes, 10),
+			strconv.FormatUint(row.BlockReadBytes, 10),
+			strconv.FormatUint(row.BlockWriteBytes, 10),
+			strconv.FormatInt(row.WallTimeMs, 10),
+		})
+	}
+	w.Flush()
+}
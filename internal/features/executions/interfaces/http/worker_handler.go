@@ -0,0 +1,26 @@
+package http
+
+import (
+	"faas/internal/features/executions/application/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkerHandler struct {
+	workerService *service.WorkerService
+}
+
+func NewWorkerHandler(service *service.WorkerService) *WorkerHandler {
+	return &WorkerHandler{workerService: service}
+}
+
+func (h *WorkerHandler) ListWorkers(c *gin.Context) {
+	workers, err := h.workerService.ListWorkers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workers)
+}
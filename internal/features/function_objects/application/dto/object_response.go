@@ -10,6 +10,7 @@ type ObjectResponse struct {
 	Name        string    `json:"name"`
 	Size        int64     `json:"size"`
 	ContentType string    `json:"content_type"`
+	SHA256      string    `json:"sha256,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -19,6 +20,7 @@ func NewObjectResponse(obj *entity.FunctionObject) *ObjectResponse {
 		Name:        obj.Name,
 		Size:        obj.Size,
 		ContentType: obj.ContentType,
+		SHA256:      obj.SHA256,
 		CreatedAt:   obj.CreatedAt,
 	}
 }
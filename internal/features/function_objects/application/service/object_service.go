@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"faas/internal/features/function_objects/application/dto"
 	"faas/internal/features/function_objects/domain/entity"
 	"faas/internal/features/function_objects/domain/repository"
@@ -21,12 +23,14 @@ func NewObjectService(repo repository.ObjectRepository) *ObjectService {
 }
 
 func (s *ObjectService) CreateObject(ctx context.Context, req *dto.CreateObjectRequest, data []byte, contentType string) (*dto.ObjectResponse, error) {
+	sum := sha256.Sum256(data)
 	obj := &entity.FunctionObject{
 		ID:          uuid.New().String(),
 		FunctionID:  req.FunctionID,
 		Name:        req.Name,
 		Size:        int64(len(data)),
 		ContentType: contentType,
+		SHA256:      hex.EncodeToString(sum[:]),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -8,6 +8,7 @@ type FunctionObject struct {
 	Name        string    `json:"name"`
 	Size        int64     `json:"size"`
 	ContentType string    `json:"content_type"`
+	SHA256      string    `json:"sha256,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -8,6 +8,8 @@ import (
 type ObjectRepository interface {
 	Save(ctx context.Context, obj *entity.FunctionObject, data []byte) error
 	Get(ctx context.Context, functionID, objectName string) (*entity.FunctionObject, []byte, error)
+	// Stat returns the metadata of an object without its data
+	Stat(ctx context.Context, functionID, objectName string) (*entity.FunctionObject, error)
 	List(ctx context.Context, functionID string) ([]*entity.FunctionObject, error)
 	Delete(ctx context.Context, functionID, objectName string) error
 }
@@ -63,6 +63,19 @@ func (r *NatsObjectRepository) Get(ctx context.Context, functionID, objectName s
 	return &obj, dataEntry.Value(), nil
 }
 
+func (r *NatsObjectRepository) Stat(ctx context.Context, functionID, objectName string) (*entity.FunctionObject, error) {
+	metaEntry, err := r.kv.Get(fmt.Sprintf("%s/%s.meta", functionID, objectName))
+	if err != nil {
+		return nil, err
+	}
+
+	var obj entity.FunctionObject
+	if err := json.Unmarshal(metaEntry.Value(), &obj); err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
 func (r *NatsObjectRepository) List(ctx context.Context, functionID string) ([]*entity.FunctionObject, error) {
 	prefix := fmt.Sprintf("%s/", functionID)
 	keys, err := r.kv.Keys()
@@ -3,23 +3,74 @@ package dto
 import "faas/internal/features/functions/domain/entity"
 
 type CreateFunctionRequest struct {
-	Name        string `json:"name" binding:"required"`
-	ImageURL    string `json:"image_url" binding:"required"`
-	Description string `json:"description"`
+	Name     string `json:"name" binding:"required"`
+	ImageURL string `json:"image_url" binding:"required"`
+	// Program and arguments to run instead of the image entrypoint, or as a
+	// local process. Only accepted with FUNCTION_COMMANDS_ENABLED.
+	Command      []string `json:"command" binding:"omitempty,dive,required"`
+	Description  string   `json:"description"`
+	Priority     string   `json:"priority" binding:"omitempty,oneof=high normal batch"`
+	RetentionTTL string   `json:"retention_ttl"`
+	// Must be https and not point at a private or link-local address
+	CallbackURL string `json:"callback_url" binding:"omitempty,url"`
+	// Concurrent executions allowed for this function, 0 for no limit
+	MaxConcurrentExecutions int `json:"max_concurrent_executions" binding:"omitempty,min=0"`
+	// Times an execution is requeued when its worker is lost
+	MaxRetries int `json:"max_retries" binding:"omitempty,min=0,max=10"`
+	// Reuse the output of earlier executions with the same input
+	CacheEnabled bool   `json:"cache_enabled"`
+	CacheTTL     string `json:"cache_ttl"`
+	// Overrides EXECUTION_TIMEOUT, e.g. "30s"
+	Timeout string `json:"timeout"`
+	// Only SANDBOX_DEFAULT_PROFILE; admins assign the others
+	SandboxProfile string `json:"sandbox_profile"`
+}
+
+type SetSandboxProfileRequest struct {
+	// One of SANDBOX_PROFILES, empty for SANDBOX_DEFAULT_PROFILE
+	SandboxProfile string `json:"sandbox_profile"`
 }
 
 type FunctionResponse struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	ImageURL string `json:"image_url"`
-	UserID   string `json:"user_id"`
+	ID                      string   `json:"id"`
+	Name                    string   `json:"name"`
+	ImageURL                string   `json:"image_url"`
+	Command                 []string `json:"command,omitempty"`
+	UserID                  string   `json:"user_id"`
+	Priority                string   `json:"priority,omitempty"`
+	RetentionTTL            string   `json:"retention_ttl,omitempty"`
+	CallbackURL             string   `json:"callback_url,omitempty"`
+	CallbackSecret          string   `json:"callback_secret,omitempty"`
+	MaxConcurrentExecutions int      `json:"max_concurrent_executions,omitempty"`
+	MaxRetries              int      `json:"max_retries,omitempty"`
+	CacheEnabled            bool     `json:"cache_enabled,omitempty"`
+	CacheTTL                string   `json:"cache_ttl,omitempty"`
+	CacheGeneration         int      `json:"cache_generation,omitempty"`
+	Timeout                 string   `json:"timeout,omitempty"`
+	SandboxProfile          string   `json:"sandbox_profile,omitempty"`
 }
 
 func NewFunctionResponse(function *entity.Function) *FunctionResponse {
 	return &FunctionResponse{
-		ID:       function.ID,
-		Name:     function.Name,
-		ImageURL: function.ImageURL,
-		UserID:   function.UserID,
+		ID:                      function.ID,
+		Name:                    function.Name,
+		ImageURL:                function.ImageURL,
+		Command:                 function.Command,
+		UserID:                  function.UserID,
+		Priority:                function.Priority,
+		RetentionTTL:            function.RetentionTTL,
+		CallbackURL:             function.CallbackURL,
+		MaxConcurrentExecutions: function.MaxConcurrentExecutions,
+		MaxRetries:              function.MaxRetries,
+		CacheEnabled:            function.CacheEnabled,
+		CacheTTL:                function.CacheTTL,
+		CacheGeneration:         function.CacheGeneration,
+		Timeout:                 function.Timeout,
+		SandboxProfile:          function.SandboxProfile,
 	}
 }
+
+// CallbackSecretResponse returns a newly generated callback secret
+type CallbackSecretResponse struct {
+	CallbackSecret string `json:"callback_secret"`
+}
@@ -2,46 +2,114 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
 	"time"
 
 	"faas/internal/features/functions/application/dto"
 	"faas/internal/features/functions/domain/entity"
 	"faas/internal/features/functions/domain/repository"
 	"faas/internal/shared/domain/errors"
+	"faas/internal/shared/infrastructure/callback"
+	"faas/internal/shared/infrastructure/config"
 
 	"github.com/google/uuid"
 )
 
+// Longest cache_ttl a function can set, the lifetime of the cache bucket entries
+const maxCacheTTL = 7 * 24 * time.Hour
+
 type FunctionService struct {
-	functionRepo repository.FunctionRepository
+	functionRepo          repository.FunctionRepository
+	sandboxProfiles       map[string]bool
+	defaultSandboxProfile string
+	commandsEnabled       bool
 }
 
-func NewFunctionService(repo repository.FunctionRepository) *FunctionService {
+func NewFunctionService(repo repository.FunctionRepository, config *config.Config) *FunctionService {
+	// The profiles themselves are defined on the workers, functions can only
+	// pick one of those the admin allows
+	sandboxProfiles := make(map[string]bool)
+	for _, name := range strings.Split(config.SandboxProfiles, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sandboxProfiles[name] = true
+		}
+	}
+
 	return &FunctionService{
-		functionRepo: repo,
+		functionRepo:          repo,
+		sandboxProfiles:       sandboxProfiles,
+		defaultSandboxProfile: config.SandboxDefaultProfile,
+		// A command runs whatever the user wants, on the worker host with
+		// the process runtime, so the admin has to allow it
+		commandsEnabled: config.FunctionCommandsEnabled,
 	}
 }
 
 func (s *FunctionService) CreateFunction(ctx context.Context, req *dto.CreateFunctionRequest, userID string) (*dto.FunctionResponse, error) {
+	if req.RetentionTTL != "" {
+		if ttl, err := time.ParseDuration(req.RetentionTTL); err != nil || ttl <= 0 {
+			return nil, errors.NewAppError("invalid_retention_ttl", "Invalid retention_ttl: "+req.RetentionTTL)
+		}
+	}
+	if req.CacheTTL != "" {
+		if ttl, err := time.ParseDuration(req.CacheTTL); err != nil || ttl <= 0 || ttl > maxCacheTTL {
+			return nil, errors.NewAppError("invalid_cache_ttl", "Invalid cache_ttl (must be a duration up to 168h): "+req.CacheTTL)
+		}
+	}
+	if req.Timeout != "" {
+		if timeout, err := time.ParseDuration(req.Timeout); err != nil || timeout <= 0 {
+			return nil, errors.NewAppError("invalid_timeout", "Invalid timeout: "+req.Timeout)
+		}
+	}
+	if len(req.Command) > 0 && !s.commandsEnabled {
+		return nil, errors.NewAppError("invalid_command", "Function commands are disabled (FUNCTION_COMMANDS_ENABLED)")
+	}
+	// Other profiles may confine less, so only admins assign them
+	if req.SandboxProfile != "" && req.SandboxProfile != s.defaultSandboxProfile {
+		return nil, errors.NewAppError("invalid_sandbox_profile", "Only admins can assign sandbox_profile "+req.SandboxProfile)
+	}
+	if req.CallbackURL != "" {
+		if err := callback.ValidateURL(req.CallbackURL); err != nil {
+			return nil, errors.NewAppError("invalid_callback_url", err.Error())
+		}
+	}
+
 	function := &entity.Function{
-		ID:          uuid.New().String(),
-		UserID:      userID,
-		Name:        req.Name,
-		ImageURL:    req.ImageURL,
-		Description: req.Description,
-		CreatedAt:   time.Now(),
+		ID:                      uuid.New().String(),
+		UserID:                  userID,
+		Name:                    req.Name,
+		ImageURL:                req.ImageURL,
+		Command:                 req.Command,
+		Description:             req.Description,
+		Priority:                req.Priority,
+		RetentionTTL:            req.RetentionTTL,
+		CallbackURL:             req.CallbackURL,
+		MaxConcurrentExecutions: req.MaxConcurrentExecutions,
+		MaxRetries:              req.MaxRetries,
+		CacheEnabled:            req.CacheEnabled,
+		CacheTTL:                req.CacheTTL,
+		Timeout:                 req.Timeout,
+		SandboxProfile:          req.SandboxProfile,
+		CreatedAt:               time.Now(),
+	}
+
+	// Every function gets a secret to sign its completion callbacks with
+	secret, err := NewCallbackSecret()
+	if err != nil {
+		return nil, err
 	}
+	function.CallbackSecret = secret
 
 	if err := s.functionRepo.Save(ctx, function); err != nil {
 		return nil, err
 	}
 
-	return &dto.FunctionResponse{
-		ID:       function.ID,
-		Name:     function.Name,
-		ImageURL: function.ImageURL,
-		UserID:   function.UserID,
-	}, nil
+	// The secret is only shown once; a lost one has to be rotated
+	response := dto.NewFunctionResponse(function)
+	response.CallbackSecret = function.CallbackSecret
+	return response, nil
 }
 
 func (s *FunctionService) GetFunction(ctx context.Context, id string, userID string) (*dto.FunctionResponse, error) {
@@ -87,3 +155,77 @@ func (s *FunctionService) DeleteFunction(ctx context.Context, id string, userID
 
 	return nil
 }
+
+// InvalidateCache discards every cached result of the function by moving it to
+// a new cache generation, which is part of every cache key
+func (s *FunctionService) InvalidateCache(ctx context.Context, id string, userID string) (*dto.FunctionResponse, error) {
+	function, err := s.functionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("function_not_found", "Function not found")
+	}
+
+	if function.UserID != userID {
+		return nil, errors.NewAppError("unauthorized", "Not authorized to modify this function")
+	}
+
+	function.CacheGeneration++
+	if err := s.functionRepo.Save(ctx, function); err != nil {
+		return nil, err
+	}
+
+	return dto.NewFunctionResponse(function), nil
+}
+
+// RotateCallbackSecret replaces the secret callbacks are signed with and
+// returns the new one. Functions created without a secret need one before
+// their callbacks are sent.
+func (s *FunctionService) RotateCallbackSecret(ctx context.Context, id string, userID string) (*dto.CallbackSecretResponse, error) {
+	function, err := s.functionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("function_not_found", "Function not found")
+	}
+
+	if function.UserID != userID {
+		return nil, errors.NewAppError("unauthorized", "Not authorized to modify this function")
+	}
+
+	secret, err := NewCallbackSecret()
+	if err != nil {
+		return nil, err
+	}
+	function.CallbackSecret = secret
+	if err := s.functionRepo.Save(ctx, function); err != nil {
+		return nil, err
+	}
+
+	return &dto.CallbackSecretResponse{CallbackSecret: secret}, nil
+}
+
+// SetSandboxProfile assigns one of the allowed sandbox profiles to a function,
+// whoever owns it. It is reserved to admins.
+func (s *FunctionService) SetSandboxProfile(ctx context.Context, id string, req *dto.SetSandboxProfileRequest) (*dto.FunctionResponse, error) {
+	if req.SandboxProfile != "" && !s.sandboxProfiles[req.SandboxProfile] {
+		return nil, errors.NewAppError("invalid_sandbox_profile", "Invalid sandbox_profile: "+req.SandboxProfile)
+	}
+
+	function, err := s.functionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("function_not_found", "Function not found")
+	}
+
+	function.SandboxProfile = req.SandboxProfile
+	if err := s.functionRepo.Save(ctx, function); err != nil {
+		return nil, err
+	}
+
+	return dto.NewFunctionResponse(function), nil
+}
+
+// NewCallbackSecret returns a random secret for signing webhook payloads
+func NewCallbackSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
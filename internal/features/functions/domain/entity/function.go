@@ -7,12 +7,24 @@ import (
 )
 
 type Function struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	Name        string    `json:"name"`
-	ImageURL    string    `json:"image_url"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID                      string    `json:"id"`
+	UserID                  string    `json:"user_id"`
+	Name                    string    `json:"name"`
+	ImageURL                string    `json:"image_url"`
+	Command                 []string  `json:"command,omitempty"`
+	Description             string    `json:"description"`
+	Priority                string    `json:"priority,omitempty"`
+	RetentionTTL            string    `json:"retention_ttl,omitempty"`
+	CallbackURL             string    `json:"callback_url,omitempty"`
+	CallbackSecret          string    `json:"callback_secret,omitempty"`
+	MaxConcurrentExecutions int       `json:"max_concurrent_executions,omitempty"`
+	MaxRetries              int       `json:"max_retries,omitempty"`
+	CacheEnabled            bool      `json:"cache_enabled,omitempty"`
+	CacheTTL                string    `json:"cache_ttl,omitempty"`
+	CacheGeneration         int       `json:"cache_generation,omitempty"`
+	Timeout                 string    `json:"timeout,omitempty"`
+	SandboxProfile          string    `json:"sandbox_profile,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
 }
 
 func NewFunction(name, imageURL, userID string) *Function {
@@ -3,9 +3,11 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"faas/internal/features/functions/application/dto"
 	"faas/internal/features/functions/application/service"
+	"faas/internal/shared/domain/errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -37,6 +39,10 @@ func (h *FunctionHandler) CreateFunction(c *gin.Context) {
 
 	function, err := h.functionService.CreateFunction(c.Request.Context(), &req, userID)
 	if err != nil {
+		if strings.HasPrefix(errors.Code(err), "invalid_") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -96,3 +102,72 @@ func (h *FunctionHandler) DeleteFunction(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+func (h *FunctionHandler) InvalidateCache(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	function, err := h.functionService.InvalidateCache(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		switch errors.Code(err) {
+		case "function_not_found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "unauthorized":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, function)
+}
+
+func (h *FunctionHandler) RotateCallbackSecret(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	secret, err := h.functionService.RotateCallbackSecret(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		switch errors.Code(err) {
+		case "function_not_found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "unauthorized":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, secret)
+}
+
+func (h *FunctionHandler) SetSandboxProfile(c *gin.Context) {
+	var req dto.SetSandboxProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	function, err := h.functionService.SetSandboxProfile(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		switch errors.Code(err) {
+		case "invalid_sandbox_profile":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "function_not_found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, function)
+}
@@ -14,5 +14,15 @@ func SetupFunctionRoutes(r *gin.Engine, handler *FunctionHandler, jwtSecret stri
 		api.GET("", handler.ListUserFunctions)
 		api.GET("/:id", handler.GetFunction)
 		api.DELETE("/:id", handler.DeleteFunction)
+		api.DELETE("/:id/cache", handler.InvalidateCache)
+		api.POST("/:id/callback-secret", handler.RotateCallbackSecret)
+	}
+}
+
+func SetupFunctionAdminRoutes(r *gin.Engine, handler *FunctionHandler, jwtSecret string) {
+	admin := r.Group("/api/admin/functions")
+	admin.Use(middleware.ExtractUserID(jwtSecret), middleware.RequireRole("admin"))
+	{
+		admin.PUT("/:id/sandbox-profile", handler.SetSandboxProfile)
 	}
 }
@@ -5,7 +5,8 @@ import "time"
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	Role     string `json:"role" binding:"required"`
+	// Only "user" can be registered; admins come from ADMIN_USERNAME
+	Role string `json:"role"`
 }
 
 type LoginRequest struct {
@@ -17,9 +18,15 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
+type SetQuotaRequest struct {
+	// 0 falls back to MAX_CONCURRENT_EXECUTIONS
+	MaxConcurrentExecutions *int `json:"max_concurrent_executions" binding:"required,min=0"`
+}
+
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                      string    `json:"id"`
+	Username                string    `json:"username"`
+	Role                    string    `json:"role"`
+	MaxConcurrentExecutions int       `json:"max_concurrent_executions,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
 }
@@ -5,6 +5,7 @@ import (
 	"faas/internal/features/users/application/dto"
 	"faas/internal/features/users/domain/entity"
 	"faas/internal/features/users/domain/repository"
+	"faas/internal/shared/domain/errors"
 	"faas/internal/shared/infrastructure/config"
 	"fmt"
 	"log"
@@ -24,7 +25,12 @@ func NewUserService(repo repository.UserRepository, cfg *config.Config) *UserSer
 	return &UserService{repo: repo, cfg: cfg}
 }
 
+// CreateUser registers a user. Registration is public, so it can't grant
+// any other role.
 func (s *UserService) CreateUser(ctx context.Context, req *dto.CreateUserRequest) (*dto.UserResponse, error) {
+	if req.Role != "" && req.Role != entity.RoleUser {
+		return nil, errors.NewAppError("invalid_role", "Only the user role can be registered")
+	}
 	log.Printf("Password before hash: %s", req.Password)
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -37,7 +43,7 @@ func (s *UserService) CreateUser(ctx context.Context, req *dto.CreateUserRequest
 		ID:        uuid.New().String(),
 		Username:  req.Username,
 		Password:  string(hashedPassword),
-		Role:      req.Role,
+		Role:      entity.RoleUser,
 		CreatedAt: time.Now(),
 	}
 
@@ -58,6 +64,39 @@ func (s *UserService) CreateUser(ctx context.Context, req *dto.CreateUserRequest
 	}, nil
 }
 
+// EnsureAdmin creates the ADMIN_USERNAME account if it doesn't exist. An
+// existing account that isn't an admin is refused rather than promoted, as
+// anyone may have registered the name. Nothing is done when ADMIN_USERNAME
+// is not set.
+func (s *UserService) EnsureAdmin(ctx context.Context) error {
+	if s.cfg.AdminUsername == "" {
+		return nil
+	}
+
+	if user, err := s.repo.GetByUsername(ctx, s.cfg.AdminUsername); err == nil {
+		if user.Role != entity.RoleAdmin {
+			return fmt.Errorf("ADMIN_USERNAME %s belongs to an account that is not an admin", user.Username)
+		}
+		return nil
+	}
+
+	if s.cfg.AdminPassword == "" {
+		return fmt.Errorf("ADMIN_PASSWORD is required to create the admin %s", s.cfg.AdminUsername)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(s.cfg.AdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	log.Printf("Creating admin %s", s.cfg.AdminUsername)
+	return s.repo.Create(ctx, &entity.User{
+		ID:        uuid.New().String(),
+		Username:  s.cfg.AdminUsername,
+		Password:  string(hashedPassword),
+		Role:      entity.RoleAdmin,
+		CreatedAt: time.Now(),
+	})
+}
+
 func (s *UserService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.LoginResponse, error) {
 	user, err := s.repo.GetByUsername(ctx, req.Username)
 	if err != nil {
@@ -100,13 +139,29 @@ func (s *UserService) GetUser(ctx context.Context, id string) (*dto.UserResponse
 	}
 
 	return &dto.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
+		ID:                      user.ID,
+		Username:                user.Username,
+		Role:                    user.Role,
+		MaxConcurrentExecutions: user.MaxConcurrentExecutions,
+		CreatedAt:               user.CreatedAt,
 	}, nil
 }
 
+// SetQuota sets how many executions the user may run at once
+func (s *UserService) SetQuota(ctx context.Context, id string, req *dto.SetQuotaRequest) (*dto.UserResponse, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewAppError("user_not_found", "User not found")
+	}
+
+	user.MaxConcurrentExecutions = *req.MaxConcurrentExecutions
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, errors.NewAppError("update_quota_failed", err.Error())
+	}
+
+	return s.GetUser(ctx, id)
+}
+
 func (s *UserService) ListUsers(ctx context.Context) ([]*dto.UserResponse, error) {
 	users, err := s.repo.List(ctx)
 	if err != nil {
@@ -116,10 +171,11 @@ func (s *UserService) ListUsers(ctx context.Context) ([]*dto.UserResponse, error
 	response := make([]*dto.UserResponse, len(users))
 	for i, user := range users {
 		response[i] = &dto.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt,
+			ID:                      user.ID,
+			Username:                user.Username,
+			Role:                    user.Role,
+			MaxConcurrentExecutions: user.MaxConcurrentExecutions,
+			CreatedAt:               user.CreatedAt,
 		}
 	}
 
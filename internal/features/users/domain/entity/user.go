@@ -2,10 +2,16 @@ package entity
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"password"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                      string    `json:"id"`
+	Username                string    `json:"username"`
+	Password                string    `json:"password"`
+	Role                    string    `json:"role"`
+	MaxConcurrentExecutions int       `json:"max_concurrent_executions,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
 }
@@ -7,6 +7,7 @@ import (
 
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
+	Update(ctx context.Context, user *entity.User) error
 	GetByID(ctx context.Context, id string) (*entity.User, error)
 	GetByUsername(ctx context.Context, username string) (*entity.User, error)
 	Delete(ctx context.Context, id string) error
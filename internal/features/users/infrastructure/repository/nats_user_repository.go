@@ -38,6 +38,16 @@ func (r *NatsUserRepository) Create(ctx context.Context, user *entity.User) erro
 	return err
 }
 
+func (r *NatsUserRepository) Update(ctx context.Context, user *entity.User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.kv.Put(user.ID, data)
+	return err
+}
+
 func (r *NatsUserRepository) GetByID(ctx context.Context, id string) (*entity.User, error) {
 	entry, err := r.kv.Get(id)
 	if err != nil {
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"faas/internal/shared/infrastructure/http/middleware"
 	"log"
 
 	"github.com/gin-gonic/gin"
@@ -26,3 +27,11 @@ func SetupUserRoutes(r *gin.Engine, handler *UserHandler) {
 		users.DELETE("/:id", handler.DeleteUser)
 	}
 }
+
+func SetupUserAdminRoutes(r *gin.Engine, handler *UserHandler, jwtSecret string) {
+	admin := r.Group("/api/admin/users")
+	admin.Use(middleware.ExtractUserID(jwtSecret), middleware.RequireRole("admin"))
+	{
+		admin.PUT("/:id/quota", handler.SetQuota)
+	}
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"faas/internal/features/users/application/dto"
 	"faas/internal/features/users/application/service"
+	"faas/internal/shared/domain/errors"
 	"log"
 	"net/http"
 
@@ -26,7 +27,12 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 	user, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch errors.Code(err) {
+		case "invalid_role":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -82,3 +88,23 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	}
 	c.Status(http.StatusNoContent)
 }
+
+func (h *UserHandler) SetQuota(c *gin.Context) {
+	var req dto.SetQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.SetQuota(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		switch errors.Code(err) {
+		case "user_not_found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
@@ -1,5 +1,7 @@
 package errors
 
+import stderrors "errors"
+
 type AppError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
@@ -15,3 +17,12 @@ func NewAppError(code, message string) *AppError {
 func (e *AppError) Error() string {
 	return e.Message
 }
+
+// Code returns the code of the AppError wrapped in err, or "" if there is none
+func Code(err error) string {
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
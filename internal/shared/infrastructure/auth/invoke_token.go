@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ScopeInvoke restricts a token to starting and reading executions. Running
+// executions get one so they can call other functions of their owner.
+const ScopeInvoke = "invoke"
+
+// Claims that scoped tokens carry on top of the user ones
+const (
+	ScopeClaim       = "scope"
+	ExecutionIDClaim = "execution_id"
+)
+
+// NewInvokeToken returns a token that lets the execution act for its owner
+// within ScopeInvoke until expiresAt. It is signed with INVOKE_TOKEN_SECRET,
+// which the API only accepts for this scope.
+func NewInvokeToken(secret, consumerKey, userID, executionID string, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":            userID,
+		ScopeClaim:       ScopeInvoke,
+		ExecutionIDClaim: executionID,
+		"iat":            time.Now().Unix(),
+		"exp":            expiresAt.Unix(),
+		"key":            consumerKey,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
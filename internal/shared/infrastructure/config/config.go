@@ -1,27 +1,116 @@
 package config
 
-import "os"
+import (
+	"faas/internal/shared/infrastructure/nats"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	ServerAddress           string
 	NatsURL                 string
 	JWTSecret               string
 	ConsumerKey             string
-	MaxConcurrentExecutions string
+	MaxConcurrentExecutions int
 	APIBaseURL              string
 	NetworkName             string
+	PriorityWeights         string
+	SchedulerInterval       time.Duration
+	RetentionTTL            time.Duration
+	RetentionInterval       time.Duration
+	RetentionArchive        bool
+	LogMaxBytes             int
+	LogCaptureStdout        bool
+	WebhookMaxAttempts      int
+	WebhookTimeout          time.Duration
+	MaxInlineOutputBytes    int
+	MaxOutputBytes          int
+	WorkerID                string
+	RateLimits              string
+	TrustedProxies          string
+	HeartbeatInterval       time.Duration
+	LeaseDuration           time.Duration
+	LeaseReaperInterval     time.Duration
+	QuotaReconcileInterval  time.Duration
+	CacheDefaultTTL         time.Duration
+	ExecutionTimeout        time.Duration
+	StopGracePeriod         time.Duration
+	ProgressMinInterval     time.Duration
+	MaxCallDepth            int
+	InvokeAPIURL            string
+	InvokeTokenSecret       string
+	AdminUsername           string
+	AdminPassword           string
+	WorkerConcurrency       int
+	DrainGracePeriod        time.Duration
+	ContainerReaperInterval time.Duration
+	WorkerRuntime           string
+	PodmanSocket            string
+	ProcessWorkDir          string
+	ProcessFunctionsDir     string
+	FunctionCommandsEnabled bool
+	SandboxProfiles         string
+	SandboxDefaultProfile   string
+	SandboxProfilesFile     string
 }
 
 func LoadConfig() *Config {
-	return &Config{
+	cfg := &Config{
 		ServerAddress:           getEnvOrDefault("SERVER_ADDRESS", ":8080"),
 		NatsURL:                 getEnvOrDefault("NATS_URL", "nats://localhost:4222"),
 		JWTSecret:               getEnvOrDefault("JWT_SECRET", "your-super-secret-key-for-development"),
 		ConsumerKey:             getEnvOrDefault("CONSUMER_KEY", "faasapp-key"),
-		MaxConcurrentExecutions: getEnvOrDefault("MAX_CONCURRENT_EXECUTIONS", "10"),
+		MaxConcurrentExecutions: getEnvIntOrDefault("MAX_CONCURRENT_EXECUTIONS", 10, 1),
 		APIBaseURL:              getEnvOrDefault("API_BASE_URL", "http://api:8080/api/function-objects"),
 		NetworkName:             getEnvOrDefault("NETWORK_NAME", "apisix"),
+		PriorityWeights:         getEnvOrDefault("PRIORITY_WEIGHTS", "high=6,normal=3,batch=1"),
+		SchedulerInterval:       getEnvDurationOrDefault("SCHEDULER_INTERVAL", time.Second, time.Millisecond),
+		RetentionTTL:            getEnvDurationOrDefault("EXECUTION_RETENTION_TTL", 0, 0),
+		RetentionInterval:       getEnvDurationOrDefault("RETENTION_INTERVAL", time.Hour, time.Millisecond),
+		RetentionArchive:        getEnvBoolOrDefault("RETENTION_ARCHIVE", true),
+		LogMaxBytes:             getEnvIntOrDefault("LOG_MAX_BYTES", 1048576, 0),
+		LogCaptureStdout:        getEnvBoolOrDefault("LOG_CAPTURE_STDOUT", false),
+		WebhookMaxAttempts:      getEnvIntOrDefault("WEBHOOK_MAX_ATTEMPTS", 8, 1),
+		WebhookTimeout:          getEnvDurationOrDefault("WEBHOOK_TIMEOUT", 10*time.Second, time.Millisecond),
+		MaxInlineOutputBytes:    getEnvIntOrDefault("MAX_INLINE_OUTPUT_BYTES", 65536, 0),
+		MaxOutputBytes:          getEnvIntOrDefault("MAX_OUTPUT_BYTES", 104857600, 1),
+		WorkerID:                getEnvOrDefault("WORKER_ID", hostname()),
+		RateLimits:              getEnvOrDefault("RATE_LIMITS", "*=120/1m,auth=20/1m,executions=60/1m,admin:*=1200/1m"),
+		TrustedProxies:          getEnvOrDefault("TRUSTED_PROXIES", ""),
+		HeartbeatInterval:       getEnvDurationOrDefault("WORKER_HEARTBEAT_INTERVAL", 5*time.Second, time.Millisecond),
+		LeaseDuration:           getEnvDurationOrDefault("EXECUTION_LEASE_DURATION", 30*time.Second, time.Millisecond),
+		LeaseReaperInterval:     getEnvDurationOrDefault("LEASE_REAPER_INTERVAL", 10*time.Second, time.Millisecond),
+		QuotaReconcileInterval:  getEnvDurationOrDefault("QUOTA_RECONCILE_INTERVAL", time.Minute, time.Millisecond),
+		CacheDefaultTTL:         getEnvDurationOrDefault("CACHE_DEFAULT_TTL", time.Hour, time.Millisecond),
+		ExecutionTimeout:        getEnvDurationOrDefault("EXECUTION_TIMEOUT", 5*time.Minute, time.Millisecond),
+		StopGracePeriod:         getEnvDurationOrDefault("STOP_GRACE_PERIOD", 10*time.Second, 0),
+		ProgressMinInterval:     getEnvDurationOrDefault("PROGRESS_MIN_INTERVAL", 2*time.Second, 0),
+		MaxCallDepth:            getEnvIntOrDefault("MAX_CALL_DEPTH", 5, 0),
+		InvokeAPIURL:            getEnvOrDefault("INVOKE_API_URL", "http://api:8080"),
+		InvokeTokenSecret:       getEnvOrDefault("INVOKE_TOKEN_SECRET", "your-invoke-token-secret-for-development"),
+		AdminUsername:           getEnvOrDefault("ADMIN_USERNAME", ""),
+		AdminPassword:           getEnvOrDefault("ADMIN_PASSWORD", ""),
+		WorkerConcurrency:       getEnvConcurrencyOrDefault("WORKER_CONCURRENCY"),
+		DrainGracePeriod:        getEnvDurationOrDefault("DRAIN_GRACE_PERIOD", 30*time.Second, 0),
+		ContainerReaperInterval: getEnvDurationOrDefault("CONTAINER_REAPER_INTERVAL", time.Minute, time.Millisecond),
+		WorkerRuntime:           getEnvOrDefault("WORKER_RUNTIME", "docker"),
+		PodmanSocket:            getEnvOrDefault("PODMAN_SOCKET", "unix:///run/podman/podman.sock"),
+		ProcessWorkDir:          getEnvOrDefault("PROCESS_WORK_DIR", ""),
+		ProcessFunctionsDir:     getEnvOrDefault("PROCESS_FUNCTIONS_DIR", ""),
+		FunctionCommandsEnabled: getEnvBoolOrDefault("FUNCTION_COMMANDS_ENABLED", false),
+		SandboxProfiles:         getEnvOrDefault("SANDBOX_PROFILES", "hardened"),
+		SandboxDefaultProfile:   getEnvOrDefault("SANDBOX_DEFAULT_PROFILE", "hardened"),
+		SandboxProfilesFile:     getEnvOrDefault("SANDBOX_PROFILES_FILE", ""),
 	}
+
+	// A worker missing two heartbeats in a row would be taken for dead
+	if cfg.HeartbeatInterval >= nats.WorkerTTL/2 {
+		log.Fatalf("Invalid WORKER_HEARTBEAT_INTERVAL %v: must be under %v", cfg.HeartbeatInterval, nats.WorkerTTL/2)
+	}
+	return cfg
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -30,3 +119,71 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvIntOrDefault parses an integer setting, refusing to start with a
+// value that is not a number or is below min
+func getEnvIntOrDefault(key string, defaultValue int, min int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min {
+		log.Fatalf("Invalid %s %q: must be an integer of at least %d", key, value, min)
+	}
+	return parsed
+}
+
+// getEnvDurationOrDefault parses a duration setting such as "30s", refusing
+// to start with a value that is not a duration or is below min
+func getEnvDurationOrDefault(key string, defaultValue time.Duration, min time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < min {
+		log.Fatalf("Invalid %s %q: must be a duration of at least %v", key, value, min)
+	}
+	return parsed
+}
+
+// getEnvBoolOrDefault parses a boolean setting, refusing to start with a
+// value strconv.ParseBool doesn't accept
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: must be true or false", key, value)
+	}
+	return parsed
+}
+
+// getEnvConcurrencyOrDefault parses a concurrency setting: a number of at
+// least 1, or "auto" (the default) for one per CPU
+func getEnvConcurrencyOrDefault(key string) int {
+	value := os.Getenv(key)
+	if value == "" || value == "auto" {
+		return runtime.NumCPU()
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		log.Fatalf("Invalid %s %q: must be auto or an integer of at least 1", key, value)
+	}
+	return parsed
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
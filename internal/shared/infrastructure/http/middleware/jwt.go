@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"errors"
+	"faas/internal/shared/infrastructure/auth"
 	"log"
 	"net/http"
 	"strings"
@@ -9,8 +11,25 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func ExtractUserID(secret string) gin.HandlerFunc {
+// errScopeNotAllowed rejects scoped tokens on routes that don't accept their scope
+var errScopeNotAllowed = errors.New("token scope not allowed")
+
+// ScopedSecret is the secret tokens of a scope are signed with. Scoped tokens
+// are minted outside the API, so a different secret keeps whoever mints them
+// from signing user tokens.
+type ScopedSecret struct {
+	Scope  string
+	Secret string
+}
+
+// ExtractUserID passes the user of the token on as X-User-ID and X-User-Role.
+// Scoped tokens are only accepted when their scope is one of scoped and they
+// are signed with its secret; the execution they were issued to is passed on
+// as X-Parent-Execution-ID.
+func ExtractUserID(secret string, scoped ...ScopedSecret) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		c.Request.Header.Del("X-Parent-Execution-ID")
+
 		// Debug logs
 		log.Printf("Using secret for validation: %s", secret)
 
@@ -23,14 +42,12 @@ func ExtractUserID(secret string) gin.HandlerFunc {
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 		log.Printf("Validating token: %s", tokenString)
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				log.Printf("Invalid signing method: %v", token.Method)
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(secret), nil
-		})
+		token, err := parseToken(tokenString, secret, scoped...)
 
+		if errors.Is(err, errScopeNotAllowed) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token scope not allowed"})
+			return
+		}
 		if err != nil {
 			log.Printf("Token validation error: %v", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
@@ -39,8 +56,15 @@ func ExtractUserID(secret string) gin.HandlerFunc {
 
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 			if sub, exists := claims["sub"].(string); exists {
+				if scope, _ := claims[auth.ScopeClaim].(string); scope != "" {
+					executionID, _ := claims[auth.ExecutionIDClaim].(string)
+					c.Request.Header.Set("X-Parent-Execution-ID", executionID)
+				}
+
 				// Set X-User-ID header
 				c.Request.Header.Set("X-User-ID", sub)
+				role, _ := claims["role"].(string)
+				c.Request.Header.Set("X-User-Role", role)
 				c.Next()
 				return
 			}
@@ -49,3 +73,26 @@ func ExtractUserID(secret string) gin.HandlerFunc {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
 	}
 }
+
+// parseToken verifies user tokens with secret and scoped tokens with the
+// secret of their scope
+func parseToken(tokenString, secret string, scoped ...ScopedSecret) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			log.Printf("Invalid signing method: %v", token.Method)
+			return nil, jwt.ErrSignatureInvalid
+		}
+
+		claims, _ := token.Claims.(jwt.MapClaims)
+		scope, _ := claims[auth.ScopeClaim].(string)
+		if scope == "" {
+			return []byte(secret), nil
+		}
+		for _, s := range scoped {
+			if s.Scope == scope && s.Secret != "" {
+				return []byte(s.Secret), nil
+			}
+		}
+		return nil, errScopeNotAllowed
+	})
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"faas/internal/shared/infrastructure/ratelimit"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// anonymousRole is the role of requests without a valid token, limited by IP
+const anonymousRole = "anonymous"
+
+// unmatchedGroup is the group of requests that match no route, so unknown
+// paths share one bucket per client
+const unmatchedGroup = "unmatched"
+
+// contentionRetryAfter is the Retry-After of requests denied because their
+// bucket is under contention
+const contentionRetryAfter = time.Second
+
+type RateLimiter interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (*ratelimit.Result, error)
+}
+
+// RateLimit applies a token bucket per user and route group, with the limit of
+// the user's role. Requests without a valid token are limited per client IP.
+// It runs before the group middlewares, so it reads the token on its own.
+// Requests are let through when the limiter is unavailable, but denied when
+// their bucket is under contention, which only a flood of requests causes.
+func RateLimit(limiter RateLimiter, limits ratelimit.Limits, secret string, scoped ...ScopedSecret) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := routeGroup(c.FullPath())
+		subject, role := requestIdentity(c, secret, scoped...)
+
+		limit, ok := limits.For(role, group)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Take(c.Request.Context(), subject+"."+group, limit)
+		if errors.Is(err, ratelimit.ErrContention) {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(contentionRetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error applying rate limit to %s: %v", subject, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Window)))
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// routeGroup returns the group a route pattern belongs to: "auth" for
+// /auth/..., otherwise the segment after /api ("functions", "executions",
+// "admin"...). Requests that match no route have an empty pattern.
+func routeGroup(path string) string {
+	if path == "" {
+		return unmatchedGroup
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if parts[0] == "api" && len(parts) > 1 {
+		return parts[1]
+	}
+	if parts[0] != "" {
+		return parts[0]
+	}
+	return ratelimit.AnyGroup
+}
+
+// requestIdentity returns the bucket subject and role of the request
+func requestIdentity(c *gin.Context, secret string, scoped ...ScopedSecret) (string, string) {
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if tokenString != "" {
+		token, err := parseToken(tokenString, secret, scoped...)
+		if err == nil && token.Valid {
+			if claims, ok := token.Claims.(jwt.MapClaims); ok {
+				if sub, ok := claims["sub"].(string); ok && sub != "" {
+					role, _ := claims["role"].(string)
+					return "user." + sub, role
+				}
+			}
+		}
+	}
+	return "ip." + c.ClientIP(), anonymousRole
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
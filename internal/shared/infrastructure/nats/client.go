@@ -1,6 +1,7 @@
 package nats
 
 import (
+	"errors"
 	"time"
 
 	natspkg "github.com/nats-io/nats.go"
@@ -12,8 +13,66 @@ const (
 	USERS_BUCKET      = "users"
 	OBJECTS_BUCKET    = "function_objects"
 	SECRETS_BUCKET    = "secrets"
+	SCHEDULES_BUCKET  = "execution_schedules"
+	INDEX_BUCKET      = "execution_index"
+	DELIVERIES_BUCKET = "webhook_deliveries"
+	QUOTAS_BUCKET     = "execution_quotas"
+	RATE_LIMIT_BUCKET = "rate_limits"
+	LEASES_BUCKET     = "execution_leases"
+	WORKERS_BUCKET    = "workers"
+	CACHE_BUCKET      = "execution_cache"
+
+	// Object store buckets
+	ARCHIVE_BUCKET = "execution_archive"
+	OUTPUTS_BUCKET = "execution_outputs"
+)
+
+const (
+	EXECUTIONS_STREAM          = "EXECUTIONS"
+	EXECUTIONS_PENDING_SUBJECT = "executions.pending"
+
+	LOGS_STREAM  = "EXECUTION_LOGS"
+	LOGS_SUBJECT = "executions.logs"
+
+	EVENTS_STREAM  = "EXECUTION_EVENTS"
+	EVENTS_SUBJECT = "executions.events"
+
+	WEBHOOKS_STREAM  = "WEBHOOKS"
+	WEBHOOKS_SUBJECT = "webhooks.deliveries"
 )
 
+// WorkerTTL is how long a worker stays registered after its last heartbeat
+const WorkerTTL = 30 * time.Second
+
+// CacheMaxTTL is how long a cached result is kept at most
+const CacheMaxTTL = 7 * 24 * time.Hour
+
+// PendingSubject returns the subject executions of the given priority are queued on
+func PendingSubject(priority string) string {
+	if priority == "" {
+		priority = "normal"
+	}
+	return EXECUTIONS_PENDING_SUBJECT + "." + priority
+}
+
+// LogSubject returns the subject the log lines of an execution are stored on
+func LogSubject(executionID string) string {
+	return LOGS_SUBJECT + "." + executionID
+}
+
+// EnqueueWebhook queues the callback of a finished execution. The message ID
+// lets JetStream drop the duplicate when an enqueue is retried.
+func EnqueueWebhook(js JetStreamContext, executionID string) error {
+	_, err := js.Publish(WEBHOOKS_SUBJECT, []byte(executionID), natspkg.MsgId(executionID))
+	return err
+}
+
+// EventSubject returns the subject execution events are published on. Pass
+// "*" for any part to build a filter.
+func EventSubject(userID, functionID, executionID string) string {
+	return EVENTS_SUBJECT + "." + userID + "." + functionID + "." + executionID
+}
+
 func Connect(url string) (*natspkg.Conn, error) {
 	return natspkg.Connect(url)
 }
@@ -64,14 +123,112 @@ func CreateBuckets(js JetStreamContext) error {
 		return err
 	}
 
+	// Bucket for scheduled executions waiting to be dispatched
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      SCHEDULES_BUCKET,
+		Description: "Scheduled executions",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket for the per-user and per-function execution indexes
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      INDEX_BUCKET,
+		Description: "Execution indexes",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket for webhook deliveries and their attempts, one per execution
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      DELIVERIES_BUCKET,
+		Description: "Webhook deliveries",
+		TTL:         7 * 24 * time.Hour,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket for the concurrency slots taken per user and per function
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      QUOTAS_BUCKET,
+		Description: "Concurrent execution quotas",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket for the API rate limit token buckets; idle keys expire
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      RATE_LIMIT_BUCKET,
+		Description: "API rate limit buckets",
+		TTL:         24 * time.Hour,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket for the leases of running executions, renewed by their workers
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      LEASES_BUCKET,
+		Description: "Execution leases",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket for worker heartbeats; workers that stop beating expire
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      WORKERS_BUCKET,
+		Description: "Worker heartbeats",
+		TTL:         WorkerTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket for cached results of deterministic functions
+	_, err = js.CreateKeyValue(&natspkg.KeyValueConfig{
+		Bucket:      CACHE_BUCKET,
+		Description: "Execution result cache",
+		TTL:         CacheMaxTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Object store for archived executions
+	_, err = js.CreateObjectStore(&natspkg.ObjectStoreConfig{
+		Bucket:      ARCHIVE_BUCKET,
+		Description: "Archived executions (gzipped JSONL)",
+		Storage:     natspkg.FileStorage,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Object store for outputs too large to be stored inline
+	_, err = js.CreateObjectStore(&natspkg.ObjectStoreConfig{
+		Bucket:      OUTPUTS_BUCKET,
+		Description: "Large execution outputs",
+		Storage:     natspkg.FileStorage,
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func CreateStreams(js JetStreamContext) error {
-	// Create persistent stream for executions
-	_, err := js.AddStream(&natspkg.StreamConfig{
-		Name:        "EXECUTIONS",
-		Subjects:    []string{"executions.pending"},
+	// Create persistent stream for executions, one subject per priority class.
+	// The bare subject holds executions queued before priority classes, which
+	// workers move to the normal class when they start.
+	err := addOrUpdateStream(js, &natspkg.StreamConfig{
+		Name:        EXECUTIONS_STREAM,
+		Subjects:    []string{EXECUTIONS_PENDING_SUBJECT, EXECUTIONS_PENDING_SUBJECT + ".*"},
 		Storage:     natspkg.FileStorage,
 		Retention:   natspkg.WorkQueuePolicy,
 		MaxAge:      24 * time.Hour,
@@ -79,5 +236,55 @@ func CreateStreams(js JetStreamContext) error {
 		AllowDirect: true,
 		AllowRollup: true,
 	})
+	if err != nil {
+		return err
+	}
+
+	// Stream with the log lines of every execution, one subject per execution
+	err = addOrUpdateStream(js, &natspkg.StreamConfig{
+		Name:        LOGS_STREAM,
+		Subjects:    []string{LOGS_SUBJECT + ".*"},
+		Storage:     natspkg.FileStorage,
+		Retention:   natspkg.LimitsPolicy,
+		MaxAge:      7 * 24 * time.Hour,
+		Discard:     natspkg.DiscardOld,
+		AllowDirect: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Short-lived stream of execution events, by user, function and execution
+	err = addOrUpdateStream(js, &natspkg.StreamConfig{
+		Name:      EVENTS_STREAM,
+		Subjects:  []string{EVENTS_SUBJECT + ".*.*.*"},
+		Storage:   natspkg.FileStorage,
+		Retention: natspkg.LimitsPolicy,
+		MaxAge:    time.Hour,
+		Discard:   natspkg.DiscardOld,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Queue of webhook deliveries waiting to be sent or retried
+	return addOrUpdateStream(js, &natspkg.StreamConfig{
+		Name:       WEBHOOKS_STREAM,
+		Subjects:   []string{WEBHOOKS_SUBJECT},
+		Storage:    natspkg.FileStorage,
+		Retention:  natspkg.WorkQueuePolicy,
+		MaxAge:     7 * 24 * time.Hour,
+		Discard:    natspkg.DiscardOld,
+		Duplicates: 10 * time.Minute,
+	})
+}
+
+// addOrUpdateStream creates the stream or, if it already exists with an
+// older configuration, updates it in place
+func addOrUpdateStream(js JetStreamContext, cfg *natspkg.StreamConfig) error {
+	_, err := js.AddStream(cfg)
+	if errors.Is(err, natspkg.ErrStreamNameAlreadyInUse) {
+		_, err = js.UpdateStream(cfg)
+	}
 	return err
 }
@@ -6,19 +6,34 @@ import natspkg "github.com/nats-io/nats.go"
 type KeyValue interface {
 	Get(key string) (KeyValueEntry, error)
 	Put(key string, value []byte) (uint64, error)
+	Create(key string, value []byte) (uint64, error)
+	Update(key string, value []byte, last uint64) (uint64, error)
 	Delete(key string, opts ...natspkg.DeleteOpt) error
 	Keys() ([]string, error)
+	Watch(keys string, opts ...natspkg.WatchOpt) (natspkg.KeyWatcher, error)
+	Status() (natspkg.KeyValueStatus, error)
 }
 
 type KeyValueEntry interface {
 	Value() []byte
+	Revision() uint64
 }
 
 type JetStreamContext interface {
 	CreateKeyValue(cfg *natspkg.KeyValueConfig) (natspkg.KeyValue, error)
 	KeyValue(bucket string) (natspkg.KeyValue, error)
 	Publish(subj string, data []byte, opts ...natspkg.PubOpt) (*natspkg.PubAck, error)
+	PublishMsg(m *natspkg.Msg, opts ...natspkg.PubOpt) (*natspkg.PubAck, error)
 	AddStream(cfg *natspkg.StreamConfig, opts ...natspkg.JSOpt) (*natspkg.StreamInfo, error)
+	UpdateStream(cfg *natspkg.StreamConfig, opts ...natspkg.JSOpt) (*natspkg.StreamInfo, error)
+	AddConsumer(stream string, cfg *natspkg.ConsumerConfig, opts ...natspkg.JSOpt) (*natspkg.ConsumerInfo, error)
+	UpdateConsumer(stream string, cfg *natspkg.ConsumerConfig, opts ...natspkg.JSOpt) (*natspkg.ConsumerInfo, error)
+	PullSubscribe(subj, durable string, opts ...natspkg.SubOpt) (*natspkg.Subscription, error)
+	SubscribeSync(subj string, opts ...natspkg.SubOpt) (*natspkg.Subscription, error)
+	GetLastMsg(name, subject string, opts ...natspkg.JSOpt) (*natspkg.RawStreamMsg, error)
+	PurgeStream(name string, opts ...natspkg.JSOpt) error
+	CreateObjectStore(cfg *natspkg.ObjectStoreConfig) (natspkg.ObjectStore, error)
+	ObjectStore(bucket string) (natspkg.ObjectStore, error)
 }
 
 // Adapter to convert nats.KeyValue to our interface
@@ -38,6 +53,14 @@ func (a *keyValueAdapter) Put(key string, value []byte) (uint64, error) {
 	return a.natsKV.Put(key, value)
 }
 
+func (a *keyValueAdapter) Create(key string, value []byte) (uint64, error) {
+	return a.natsKV.Create(key, value)
+}
+
+func (a *keyValueAdapter) Update(key string, value []byte, last uint64) (uint64, error) {
+	return a.natsKV.Update(key, value, last)
+}
+
 func (a *keyValueAdapter) Delete(key string, opts ...natspkg.DeleteOpt) error {
 	return a.natsKV.Delete(key, opts...)
 }
@@ -45,3 +68,11 @@ func (a *keyValueAdapter) Delete(key string, opts ...natspkg.DeleteOpt) error {
 func (a *keyValueAdapter) Keys() ([]string, error) {
 	return a.natsKV.Keys()
 }
+
+func (a *keyValueAdapter) Watch(keys string, opts ...natspkg.WatchOpt) (natspkg.KeyWatcher, error) {
+	return a.natsKV.Watch(keys, opts...)
+}
+
+func (a *keyValueAdapter) Status() (natspkg.KeyValueStatus, error) {
+	return a.natsKV.Status()
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnyGroup matches every route group in a limit spec
+const AnyGroup = "*"
+
+// Limit allows Requests per Window, refilled continuously. Requests is also
+// the burst a client can spend at once.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Rate returns the tokens refilled per second
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Limits holds the limit of every role and route group
+type Limits map[string]Limit
+
+// ParseLimits parses "*=120/1m,executions=60/1m,admin:*=1200/1m". Keys are a
+// route group or role:group, "*" matching any; a value of 0 disables the limit.
+func ParseLimits(spec string) (Limits, error) {
+	limits := Limits{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid rate limit %q", part)
+		}
+		key = strings.TrimSpace(key)
+
+		if strings.TrimSpace(value) == "0" {
+			limits[key] = Limit{}
+			continue
+		}
+
+		requests, window, found := strings.Cut(value, "/")
+		n, err := strconv.Atoi(strings.TrimSpace(requests))
+		if !found || err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid rate limit %q", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q", part)
+		}
+		limits[key] = Limit{Requests: n, Window: d}
+	}
+	return limits, nil
+}
+
+// For returns the most specific limit for the role and group: role:group,
+// role:*, group, then *. ok is false when no limit applies.
+func (l Limits) For(role, group string) (Limit, bool) {
+	for _, key := range []string{role + ":" + group, role + ":" + AnyGroup, group, AnyGroup} {
+		if limit, found := l[key]; found {
+			return limit, limit.Requests > 0
+		}
+	}
+	return Limit{}, false
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    Limits
+		wantErr bool
+	}{
+		{
+			name: "groups and roles",
+			spec: "*=120/1m,auth=20/1m,admin:*=1200/1m",
+			want: Limits{
+				"*":       {Requests: 120, Window: time.Minute},
+				"auth":    {Requests: 20, Window: time.Minute},
+				"admin:*": {Requests: 1200, Window: time.Minute},
+			},
+		},
+		{
+			name: "zero disables",
+			spec: "executions=0",
+			want: Limits{"executions": {}},
+		},
+		{
+			name: "spaces are trimmed",
+			spec: " auth = 5 / 30s , ",
+			want: Limits{"auth": {Requests: 5, Window: 30 * time.Second}},
+		},
+		{name: "missing value", spec: "g=10/1h,a", wantErr: true},
+		{name: "invalid requests", spec: "b=x/1m", wantErr: true},
+		{name: "missing window", spec: "c=5", wantErr: true},
+		{name: "invalid window", spec: "d=5/forever", wantErr: true},
+		{name: "negative requests", spec: "e=-1/1m", wantErr: true},
+		{name: "zero window", spec: "f=5/0s", wantErr: true},
+		{
+			name: "empty",
+			spec: "",
+			want: Limits{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimits(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimits(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLimits(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitsFor(t *testing.T) {
+	limits := Limits{
+		"*":                {Requests: 120, Window: time.Minute},
+		"auth":             {Requests: 20, Window: time.Minute},
+		"admin:*":          {Requests: 1200, Window: time.Minute},
+		"admin:executions": {Requests: 600, Window: time.Minute},
+		"user:functions":   {},
+	}
+
+	tests := []struct {
+		name   string
+		role   string
+		group  string
+		want   Limit
+		wantOK bool
+	}{
+		{name: "role and group", role: "admin", group: "executions", want: limits["admin:executions"], wantOK: true},
+		{name: "role and any group", role: "admin", group: "auth", want: limits["admin:*"], wantOK: true},
+		{name: "group", role: "user", group: "auth", want: limits["auth"], wantOK: true},
+		{name: "any group", role: "user", group: "executions", want: limits["*"], wantOK: true},
+		{name: "anonymous", role: "", group: "auth", want: limits["auth"], wantOK: true},
+		{name: "disabled", role: "user", group: "functions", want: Limit{}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := limits.For(tt.role, tt.group)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("For(%q, %q) = %v, %v, want %v, %v", tt.role, tt.group, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := (Limits{}).For("user", "auth"); ok {
+		t.Error("For on empty limits applied a limit")
+	}
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/shared/infrastructure/nats"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+// Attempts of a compare-and-set update before giving up under contention
+const maxAttempts = 20
+
+// ErrContention is returned when the bucket changed on every attempt, i.e.
+// a client is hammering it from several replicas
+var ErrContention = errors.New("too much contention")
+
+// Result describes a bucket after taking a request from it
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, when denied
+}
+
+// bucket is the stored state of a token bucket
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// NatsLimiter keeps token buckets in KV so every API replica shares them.
+// Updates are compare-and-set on the key revision.
+type NatsLimiter struct {
+	kv nats.KeyValue
+}
+
+func NewNatsLimiter(js nats.JetStreamContext) (*NatsLimiter, error) {
+	kv, err := js.KeyValue(nats.RATE_LIMIT_BUCKET)
+	if err != nil {
+		return nil, err
+	}
+	return &NatsLimiter{kv: nats.NewKeyValueAdapter(kv)}, nil
+}
+
+// Take refills the bucket for the time elapsed and takes one token from it
+func (l *NatsLimiter) Take(ctx context.Context, key string, limit Limit) (*Result, error) {
+	key = sanitizeKey(key)
+	capacity := float64(limit.Requests)
+	rate := limit.Rate()
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		state := &bucket{Tokens: capacity, Updated: now}
+		var revision uint64
+		entry, err := l.kv.Get(key)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(entry.Value(), state); err != nil {
+				return nil, err
+			}
+			revision = entry.Revision()
+		case !errors.Is(err, natspkg.ErrKeyNotFound):
+			return nil, err
+		}
+
+		elapsed := now.Sub(state.Updated).Seconds()
+		if elapsed > 0 {
+			state.Tokens = math.Min(capacity, state.Tokens+elapsed*rate)
+		}
+		state.Updated = now
+
+		result := &Result{}
+		if state.Tokens >= 1 {
+			state.Tokens--
+			result.Allowed = true
+		} else {
+			result.RetryAfter = seconds((1 - state.Tokens) / rate)
+		}
+		result.Remaining = int(state.Tokens)
+		result.Reset = seconds((capacity - state.Tokens) / rate)
+
+		// A denied request doesn't change the bucket
+		if !result.Allowed {
+			return result, nil
+		}
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			return nil, err
+		}
+		if revision == 0 {
+			_, err = l.kv.Create(key, data)
+		} else {
+			_, err = l.kv.Update(key, data, revision)
+		}
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, natspkg.ErrKeyExists) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("rate limit %s: %w", key, ErrContention)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// sanitizeKey replaces the characters KV keys can't hold, like the colons of
+// IPv6 addresses
+func sanitizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-', r == '_', r == '.', r == '=':
+			return r
+		}
+		return '_'
+	}, key)
+}
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"faas/internal/shared/infrastructure/nats"
+	"testing"
+	"time"
+
+	natspkg "github.com/nats-io/nats.go"
+)
+
+type fakeEntry struct {
+	value    []byte
+	revision uint64
+}
+
+func (e *fakeEntry) Value() []byte    { return e.value }
+func (e *fakeEntry) Revision() uint64 { return e.revision }
+
+// fakeKV keeps entries in memory with revisions like a KV bucket. Writes fail
+// with ErrKeyExists while conflicts is positive, as if another replica won.
+type fakeKV struct {
+	nats.KeyValue
+	entries   map[string]*fakeEntry
+	revision  uint64
+	conflicts int
+	getErr    error
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{entries: map[string]*fakeEntry{}}
+}
+
+func (kv *fakeKV) Get(key string) (nats.KeyValueEntry, error) {
+	if kv.getErr != nil {
+		return nil, kv.getErr
+	}
+	entry, ok := kv.entries[key]
+	if !ok {
+		return nil, natspkg.ErrKeyNotFound
+	}
+	return entry, nil
+}
+
+func (kv *fakeKV) Create(key string, value []byte) (uint64, error) {
+	if _, ok := kv.entries[key]; ok || kv.conflict() {
+		return 0, natspkg.ErrKeyExists
+	}
+	return kv.put(key, value), nil
+}
+
+func (kv *fakeKV) Update(key string, value []byte, last uint64) (uint64, error) {
+	entry, ok := kv.entries[key]
+	if !ok || entry.revision != last || kv.conflict() {
+		return 0, natspkg.ErrKeyExists
+	}
+	return kv.put(key, value), nil
+}
+
+func (kv *fakeKV) conflict() bool {
+	if kv.conflicts > 0 {
+		kv.conflicts--
+		return true
+	}
+	return false
+}
+
+func (kv *fakeKV) put(key string, value []byte) uint64 {
+	kv.revision++
+	kv.entries[key] = &fakeEntry{value: value, revision: kv.revision}
+	return kv.revision
+}
+
+func TestNatsLimiterTake(t *testing.T) {
+	limit := Limit{Requests: 3, Window: time.Hour}
+
+	tests := []struct {
+		name        string
+		conflicts   int
+		getErr      error
+		takes       int
+		wantAllowed bool
+		wantErr     error
+	}{
+		{name: "first request", takes: 1, wantAllowed: true},
+		{name: "burst up to the limit", takes: 3, wantAllowed: true},
+		{name: "over the limit", takes: 4, wantAllowed: false},
+		{name: "retries after a conflict", conflicts: 2, takes: 1, wantAllowed: true},
+		{name: "gives up under contention", conflicts: maxAttempts, takes: 1, wantErr: ErrContention},
+		{name: "KV unreachable", getErr: natspkg.ErrConnectionClosed, takes: 1, wantErr: natspkg.ErrConnectionClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := newFakeKV()
+			kv.conflicts = tt.conflicts
+			kv.getErr = tt.getErr
+			limiter := &NatsLimiter{kv: kv}
+
+			var result *Result
+			var err error
+			for i := 0; i < tt.takes; i++ {
+				result, err = limiter.Take(context.Background(), "user.u1.executions", limit)
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Take() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+			if result.Allowed != tt.wantAllowed {
+				t.Errorf("Take() allowed = %v, want %v", result.Allowed, tt.wantAllowed)
+			}
+			if !result.Allowed && result.RetryAfter <= 0 {
+				t.Errorf("Take() denied with RetryAfter %v", result.RetryAfter)
+			}
+			if want := limit.Requests - tt.takes; result.Allowed && result.Remaining != want {
+				t.Errorf("Take() remaining = %d, want %d", result.Remaining, want)
+			}
+		})
+	}
+}
+
+func TestSanitizeKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "ip.10.0.0.1.auth", want: "ip.10.0.0.1.auth"},
+		{key: "ip.2001:db8::1.auth", want: "ip.2001_db8__1.auth"},
+		{key: "user.a b/c*", want: "user.a_b_c_"},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeKey(tt.key); got != tt.want {
+			t.Errorf("sanitizeKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ContainerReaperService removes function containers nobody will clean up:
+// those of executions that finished, no longer exist or moved on to a later
+// attempt. Workers share the Docker host, so the containers of executions
+// still running elsewhere are left alone.
+type ContainerReaperService struct {
+	containerManager ports.ContainerManager
+	executionRepo    ports.ExecutionRepository
+	executions       *ExecutionService
+	interval         time.Duration
+}
+
+func NewContainerReaperService(containerManager ports.ContainerManager, executionRepo ports.ExecutionRepository, executions *ExecutionService, config *config.Config) *ContainerReaperService {
+	return &ContainerReaperService{
+		containerManager: containerManager,
+		executionRepo:    executionRepo,
+		executions:       executions,
+		interval:         config.ContainerReaperInterval,
+	}
+}
+
+func (s *ContainerReaperService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.reap(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reap(ctx)
+		}
+	}
+}
+
+func (s *ContainerReaperService) reap(ctx context.Context) {
+	containers, err := s.containerManager.ListContainers(ctx)
+	if err != nil {
+		log.Printf("Error listing function containers: %v", err)
+		return
+	}
+
+	active := make(map[string]bool)
+	for _, id := range s.executions.ActiveExecutions() {
+		active[id] = true
+	}
+
+	removed := 0
+	for _, container := range containers {
+		if active[container.ExecutionID] {
+			continue
+		}
+
+		reason, err := s.orphaned(ctx, container.ExecutionID, container.Attempt)
+		if err != nil {
+			log.Printf("Error checking container %s of execution %s: %v", container.ID, container.ExecutionID, err)
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+
+		if err := s.containerManager.RemoveContainer(ctx, container.ID); err != nil {
+			log.Printf("Error removing container %s of execution %s: %v", container.ID, container.ExecutionID, err)
+			continue
+		}
+		removed++
+		log.Printf("Removed container %s (%s, created %s by worker %s) of execution %s: %s",
+			container.ID, container.Status, container.CreatedAt.Format(time.RFC3339), container.WorkerID, container.ExecutionID, reason)
+	}
+
+	if removed > 0 {
+		log.Printf("Removed %d of %d function containers", removed, len(containers))
+	}
+}
+
+// orphaned returns why the container of an execution attempt is no longer
+// needed, or "" if it may still be running it
+func (s *ContainerReaperService) orphaned(ctx context.Context, executionID string, attempt int) (string, error) {
+	if executionID == "" {
+		return "no execution label", nil
+	}
+
+	execution, err := s.executionRepo.GetExecution(ctx, executionID)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case execution == nil:
+		return "unknown execution", nil
+	case execution.IsTerminal():
+		return "execution " + string(execution.Status), nil
+	case attempt < execution.Attempt:
+		return fmt.Sprintf("superseded by attempt %d", execution.Attempt+1), nil
+	}
+	return "", nil
+}
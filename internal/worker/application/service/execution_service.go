@@ -2,51 +2,413 @@ package service
 
 import (
 	"context"
+	"errors"
 	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/config"
 	"faas/internal/worker/domain/ports"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
 	"time"
 )
 
+// Time allowed for handing an interrupted execution back to the queue
+const requeueTimeout = 10 * time.Second
+
 type ExecutionService struct {
 	containerManager ports.ContainerManager
 	executionRepo    ports.ExecutionRepository
+	queue            ports.ExecutionQueue
+	logRepo          ports.LogRepository
+	events           ports.EventPublisher
+	outputStore      ports.OutputStore
+	quotaRepo        ports.QuotaRepository
+	leaseRepo        ports.LeaseRepository
+	cacheStore       ports.CacheStore
+	progress         *ProgressService
+	maxInlineOutput  int
+	leaseDuration    time.Duration
+	workerID         string
+
+	mu     sync.Mutex
+	active map[string]struct{}
 }
 
 func NewExecutionService(
 	containerManager ports.ContainerManager,
 	executionRepo ports.ExecutionRepository,
+	queue ports.ExecutionQueue,
+	logRepo ports.LogRepository,
+	events ports.EventPublisher,
+	outputStore ports.OutputStore,
+	quotaRepo ports.QuotaRepository,
+	leaseRepo ports.LeaseRepository,
+	cacheStore ports.CacheStore,
+	progress *ProgressService,
+	config *config.Config,
 ) *ExecutionService {
 	return &ExecutionService{
 		containerManager: containerManager,
 		executionRepo:    executionRepo,
+		queue:            queue,
+		logRepo:          logRepo,
+		events:           events,
+		outputStore:      outputStore,
+		quotaRepo:        quotaRepo,
+		leaseRepo:        leaseRepo,
+		cacheStore:       cacheStore,
+		progress:         progress,
+		maxInlineOutput:  config.MaxInlineOutputBytes,
+		leaseDuration:    config.LeaseDuration,
+		workerID:         config.WorkerID,
+		active:           make(map[string]struct{}),
 	}
 }
 
 func (s *ExecutionService) ProcessExecution(ctx context.Context, execution *entity.Execution) error {
-	// 1. Update status to "running"
+	// A recovery may publish an attempt more than once, so the stored record
+	// decides whether this message still has anything to run
+	current, err := s.executionRepo.GetExecution(ctx, execution.ID)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.Status != entity.StatusPending || current.Attempt != execution.Attempt {
+		log.Printf("Skipping stale message of execution %s (attempt %d)", execution.ID, execution.Attempt)
+		return nil
+	}
+	*execution = *current
+
+	// 1. Take the lease and update status to "running"
 	now := time.Now()
+	lease := &entity.Lease{
+		ExecutionID: execution.ID,
+		WorkerID:    s.workerID,
+		Attempt:     execution.Attempt,
+		ExpiresAt:   now.Add(s.leaseDuration),
+	}
+	if err := s.leaseRepo.Acquire(ctx, lease); err != nil {
+		if errors.Is(err, entity.ErrLeaseLost) {
+			log.Printf("Execution %s (attempt %d) already runs elsewhere", execution.ID, execution.Attempt)
+			return nil
+		}
+		s.failToStart(execution, err)
+		return err
+	}
+
 	execution.Status = entity.StatusRunning
 	execution.StartedAt = &now
+	execution.Lease = lease
+	execution.Record(entity.PhasePickedUp, s.workerID)
 
 	if err := s.executionRepo.UpdateExecution(ctx, execution); err != nil {
+		s.releaseLease(ctx, lease)
+		s.failToStart(execution, err)
 		return err
 	}
+	s.publishStatus(ctx, execution)
+
+	s.track(execution.ID, true)
+	defer s.track(execution.ID, false)
+
+	// 2. Execute function, renewing the lease until it returns
+	runCtx, cancel := context.WithCancel(ctx)
+	renewed := make(chan bool, 1)
+	go func() {
+		renewed <- s.renewLease(runCtx, cancel, lease)
+	}()
+
+	s.progress.Track(execution)
+	output, err := s.containerManager.RunFunction(runCtx, execution)
+	cancel()
+	progress, statusMessage := s.progress.Finish(execution.ID)
+	if !<-renewed {
+		// The execution was recovered elsewhere and is no longer ours
+		log.Printf("Lease of execution %s lost, discarding its result", execution.ID)
+		return nil
+	}
+	if ctx.Err() != nil {
+		// The worker is shutting down and the execution was interrupted
+		s.requeue(execution, lease)
+		return nil
+	}
 
-	// 2. Execute function
-	output, err := s.containerManager.RunFunction(ctx, execution)
 	now = time.Now()
 	execution.CompletedAt = &now
+	execution.Lease = nil
 
 	if err != nil {
 		// 3a. If there is an error, update status to "failed"
 		execution.Status = entity.StatusFailed
 		execution.Error = err.Error()
+		execution.FailureClass = classifyFailure(err)
 	} else {
 		// 3b. If no error, update status to "completed"
 		execution.Status = entity.StatusCompleted
+	}
+
+	// Keep the last reported progress; a function that reported any is done
+	execution.Progress = progress
+	execution.StatusMessage = statusMessage
+	if progress != nil && execution.Status == entity.StatusCompleted {
+		done := 100
+		execution.Progress = &done
+	}
+
+	if err := s.setOutput(ctx, execution, output); err != nil {
+		execution.Status = entity.StatusFailed
+		execution.Error = "storing output: " + err.Error()
+		execution.FailureClass = entity.FailureInfrastructure
+	}
+
+	// 4. Save final result. The lease is given up first: if the reaper took
+	// it since the last renewal, the execution was recovered and the result
+	// must not overwrite what it saved.
+	if !s.releaseLease(ctx, lease) {
+		log.Printf("Lease of execution %s lost, discarding its result", execution.ID)
+		return nil
+	}
+	execution.Record(entity.PhasePersisted, "")
+	if err := s.executionRepo.UpdateExecution(ctx, execution); err != nil {
+		s.restoreLease(lease)
+		return err
+	}
+	s.publishStatus(ctx, execution)
+	s.releaseQuota(ctx, execution)
+	s.cacheResult(ctx, execution)
+
+	// 5. Tell log followers that no more lines are coming
+	if err := s.logRepo.End(ctx, execution.ID); err != nil {
+		log.Printf("Error ending logs of execution %s: %v", execution.ID, err)
+	}
+	return nil
+}
+
+// requeue hands an execution interrupted by a shutdown to another worker as
+// its next attempt. Its quota slots stay reserved.
+func (s *ExecutionService) requeue(execution *entity.Execution, lease *entity.Lease) {
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+	defer cancel()
+
+	if !s.releaseLease(ctx, lease) {
+		log.Printf("Lease of execution %s lost, not requeueing it", execution.ID)
+		return
+	}
+
+	execution.Attempt++
+	execution.Status = entity.StatusPending
+	execution.StartedAt = nil
+	execution.Lease = nil
+	execution.Usage = nil
+	execution.Progress = nil
+	execution.StatusMessage = ""
+	execution.Record(entity.PhaseRequeued, fmt.Sprintf("worker %s shut down", s.workerID))
+
+	// The record is saved before the execution is published, so the next
+	// worker can't be overwritten
+	if err := s.executionRepo.UpdateExecution(ctx, execution); err != nil {
+		log.Printf("Error requeueing execution %s: %v", execution.ID, err)
+		s.restoreLease(lease)
+		return
+	}
+	if err := s.queue.PublishPending(execution); err != nil {
+		log.Printf("Error requeueing execution %s: %v", execution.ID, err)
+		s.failToStart(execution, err)
+		return
+	}
+	s.publishStatus(ctx, execution)
+	log.Printf("Requeued execution %s (attempt %d) on shutdown", execution.ID, execution.Attempt)
+}
+
+// failToStart fails an execution whose attempt couldn't be started. Its
+// message isn't delivered again, so otherwise the execution would stay
+// pending and keep its quota slots for good. The stored record is checked
+// first, as a stale message must not fail an execution that moved on.
+func (s *ExecutionService) failToStart(execution *entity.Execution, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+	defer cancel()
+
+	current, err := s.executionRepo.GetExecution(ctx, execution.ID)
+	if err != nil {
+		log.Printf("Error failing execution %s, it may stay pending: %v", execution.ID, err)
+		return
+	}
+	if current == nil || current.Attempt != execution.Attempt || current.IsTerminal() {
+		return
+	}
+	if current.Status == entity.StatusRunning && current.Lease != nil && current.Lease.WorkerID != s.workerID {
+		return
+	}
+
+	now := time.Now()
+	current.Status = entity.StatusFailed
+	current.Error = "starting execution: " + cause.Error()
+	current.FailureClass = entity.FailureInfrastructure
+	current.CompletedAt = &now
+	current.Lease = nil
+	current.Record(entity.PhasePersisted, "")
+	if err := s.executionRepo.UpdateExecution(ctx, current); err != nil {
+		log.Printf("Error failing execution %s, it may stay pending: %v", execution.ID, err)
+		return
+	}
+	s.publishStatus(ctx, current)
+	s.releaseQuota(ctx, current)
+	if err := s.logRepo.End(ctx, current.ID); err != nil {
+		log.Printf("Error ending logs of execution %s: %v", current.ID, err)
+	}
+	log.Printf("Failed execution %s: could not start: %v", current.ID, cause)
+}
+
+// cacheResult stores the output of a successful execution under its cache
+// key. Only inline outputs are cached, as offloaded ones may be purged. The
+// digest every run resolved is recorded, so results of the image a tag
+// pointed to before are no longer reused.
+func (s *ExecutionService) cacheResult(ctx context.Context, execution *entity.Execution) {
+	if execution.CacheKey == "" {
+		return
+	}
+	// Reruns pinned to an earlier digest do not say where the tag points now
+	if execution.ImageDigest != "" && execution.PinnedImage == "" {
+		if err := s.cacheStore.SetLatestDigest(ctx, execution.FunctionID, execution.ImageDigest); err != nil {
+			log.Printf("Error recording image digest of function %s: %v", execution.FunctionID, err)
+		}
+	}
+	if execution.Status != entity.StatusCompleted || execution.OutputRef != nil {
+		return
+	}
+
+	err := s.cacheStore.Put(ctx, &entity.CacheEntry{
+		Key:         execution.CacheKey,
+		ExecutionID: execution.ID,
+		FunctionID:  execution.FunctionID,
+		Output:      execution.Output,
+		ImageDigest: execution.ImageDigest,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error caching result of execution %s: %v", execution.ID, err)
+	}
+}
+
+// ActiveExecutions returns the IDs of the executions running on this worker
+func (s *ExecutionService) ActiveExecutions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.active))
+	for id := range s.active {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *ExecutionService) track(executionID string, running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if running {
+		s.active[executionID] = struct{}{}
+	} else {
+		delete(s.active, executionID)
+	}
+}
+
+// renewLease extends the lease every third of its duration until ctx is done.
+// If the lease is lost it cancels the run and returns false.
+func (s *ExecutionService) renewLease(ctx context.Context, cancel context.CancelFunc, lease *entity.Lease) bool {
+	ticker := time.NewTicker(s.leaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-ticker.C:
+			renewal := *lease
+			renewal.ExpiresAt = time.Now().Add(s.leaseDuration)
+			err := s.leaseRepo.Renew(ctx, &renewal)
+			switch {
+			case err == nil:
+				lease.ExpiresAt = renewal.ExpiresAt
+			case errors.Is(err, entity.ErrLeaseLost):
+				cancel()
+				return false
+			case ctx.Err() == nil:
+				// A transient error; the next tick tries again before expiry
+				log.Printf("Error renewing lease of execution %s: %v", lease.ExecutionID, err)
+			}
+		}
+	}
+}
+
+// releaseLease gives up the lease, reporting whether this worker still held
+// it. If that can't be told, the lease is left to expire and the reaper
+// recovers the execution.
+func (s *ExecutionService) releaseLease(ctx context.Context, lease *entity.Lease) bool {
+	released, err := s.leaseRepo.Release(ctx, lease)
+	if err != nil {
+		log.Printf("Error releasing lease of execution %s: %v", lease.ExecutionID, err)
+		return false
+	}
+	return released
+}
+
+// restoreLease puts back an already expired lease after a result couldn't be
+// saved, so the reaper recovers the execution instead of leaving it running
+func (s *ExecutionService) restoreLease(lease *entity.Lease) {
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+	defer cancel()
+
+	expired := *lease
+	expired.ExpiresAt = time.Now()
+	if err := s.leaseRepo.Acquire(ctx, &expired); err != nil {
+		log.Printf("Error restoring lease of execution %s: %v", lease.ExecutionID, err)
+	}
+}
+
+// setOutput keeps small outputs inline and moves larger ones to the output
+// store, referenced by size and checksum
+func (s *ExecutionService) setOutput(ctx context.Context, execution *entity.Execution, output string) error {
+	if len(output) <= s.maxInlineOutput {
 		execution.Output = output
+		return nil
 	}
 
-	// 4. Save final result
-	return s.executionRepo.UpdateExecution(ctx, execution)
+	ref, err := s.outputStore.Put(ctx, execution.ID, []byte(output))
+	if err != nil {
+		return err
+	}
+	execution.Output = ""
+	execution.OutputRef = ref
+	return nil
+}
+
+func (s *ExecutionService) publishStatus(ctx context.Context, execution *entity.Execution) {
+	if err := s.events.Publish(ctx, entity.NewStatusEvent(execution)); err != nil {
+		log.Printf("Error publishing status event of execution %s: %v", execution.ID, err)
+	}
+}
+
+// releaseQuota frees the concurrency slots reserved when the execution was queued
+func (s *ExecutionService) releaseQuota(ctx context.Context, execution *entity.Execution) {
+	for _, key := range execution.QuotaKeys() {
+		if err := s.quotaRepo.Release(ctx, key, execution.ID); err != nil {
+			log.Printf("Error releasing quota %s of execution %s: %v", key, execution.ID, err)
+		}
+	}
+}
+
+func classifyFailure(err error) entity.FailureClass {
+	var exitErr *ports.ExitError
+	switch {
+	case errors.Is(err, ports.ErrExecutionTimeout):
+		return entity.FailureTimeout
+	case errors.Is(err, ports.ErrOutputTooLarge):
+		return entity.FailureFunctionError
+	case errors.As(err, &exitErr):
+		return entity.FailureFunctionError
+	default:
+		return entity.FailureInfrastructure
+	}
 }
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"log"
+	"time"
+)
+
+// HeartbeatService keeps the worker registered, with the executions it is
+// running, until its context is done
+type HeartbeatService struct {
+	registry   ports.WorkerRegistry
+	executions *ExecutionService
+	interval   time.Duration
+	worker     *entity.Worker
+}
+
+func NewHeartbeatService(registry ports.WorkerRegistry, executions *ExecutionService, config *config.Config) *HeartbeatService {
+	return &HeartbeatService{
+		registry:   registry,
+		executions: executions,
+		interval:   config.HeartbeatInterval,
+		worker: &entity.Worker{
+			ID:        config.WorkerID,
+			Runtime:   config.WorkerRuntime,
+			StartedAt: time.Now(),
+		},
+	}
+}
+
+func (s *HeartbeatService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.beat(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.registry.Deregister(context.Background(), s.worker.ID); err != nil {
+				log.Printf("Error deregistering worker %s: %v", s.worker.ID, err)
+			}
+			return
+		case <-ticker.C:
+			s.beat(ctx)
+		}
+	}
+}
+
+func (s *HeartbeatService) beat(ctx context.Context) {
+	s.worker.LastHeartbeat = time.Now()
+	s.worker.Executions = s.executions.ActiveExecutions()
+	if err := s.registry.Heartbeat(ctx, s.worker); err != nil {
+		log.Printf("Error sending heartbeat of worker %s: %v", s.worker.ID, err)
+	}
+}
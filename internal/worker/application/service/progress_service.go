@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"log"
+	"sync"
+	"time"
+)
+
+// Time allowed for storing and announcing one progress update
+const progressWriteTimeout = 5 * time.Second
+
+// ProgressService stores the progress reported by running functions. Each
+// execution is written at most once per minInterval; reports in between only
+// keep the latest values, which are written when the interval is up.
+type ProgressService struct {
+	executionRepo ports.ExecutionRepository
+	events        ports.EventPublisher
+	minInterval   time.Duration
+
+	mu      sync.Mutex
+	tracked map[string]*progressState
+}
+
+type progressState struct {
+	execution entity.Execution
+	pending   bool
+	lastWrite time.Time
+	timer     *time.Timer
+}
+
+func NewProgressService(executionRepo ports.ExecutionRepository, events ports.EventPublisher, config *config.Config) *ProgressService {
+	return &ProgressService{
+		executionRepo: executionRepo,
+		events:        events,
+		minInterval:   config.ProgressMinInterval,
+		tracked:       make(map[string]*progressState),
+	}
+}
+
+// Track starts accepting progress reports for the execution
+func (s *ProgressService) Track(execution *entity.Execution) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tracked[execution.ID] = &progressState{
+		execution: entity.Execution{
+			ID:         execution.ID,
+			FunctionID: execution.FunctionID,
+			UserID:     execution.UserID,
+			Status:     entity.StatusRunning,
+		},
+	}
+}
+
+// Finish stops accepting reports for the execution, drops any update still
+// waiting for its interval and returns the latest reported values so they
+// can be saved with the final result
+func (s *ProgressService) Finish(executionID string) (*int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.tracked[executionID]
+	if !ok {
+		return nil, ""
+	}
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	delete(s.tracked, executionID)
+	return state.execution.Progress, state.execution.StatusMessage
+}
+
+// Report records the progress of a tracked execution and schedules a write
+func (s *ProgressService) Report(executionID string, progress *int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.tracked[executionID]
+	if !ok {
+		return
+	}
+	if progress != nil {
+		state.execution.Progress = progress
+	}
+	state.execution.StatusMessage = message
+	state.pending = true
+
+	if state.timer != nil {
+		return
+	}
+	wait := s.minInterval - time.Since(state.lastWrite)
+	if wait < 0 {
+		wait = 0
+	}
+	state.timer = time.AfterFunc(wait, func() { s.flush(executionID) })
+}
+
+// flush writes the latest values of the execution, outside of the lock so a
+// slow write doesn't hold up the log reader
+func (s *ProgressService) flush(executionID string) {
+	s.mu.Lock()
+	state, ok := s.tracked[executionID]
+	if !ok || !state.pending {
+		s.mu.Unlock()
+		return
+	}
+	state.timer = nil
+	state.pending = false
+	state.lastWrite = time.Now()
+	execution := state.execution
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), progressWriteTimeout)
+	defer cancel()
+
+	updated, err := s.executionRepo.UpdateProgress(ctx, executionID, execution.Progress, execution.StatusMessage)
+	if err != nil {
+		log.Printf("Error storing progress of execution %s: %v", executionID, err)
+		return
+	}
+	if !updated {
+		return
+	}
+	if err := s.events.Publish(ctx, entity.NewProgressEvent(&execution)); err != nil {
+		log.Printf("Error publishing progress event of execution %s: %v", executionID, err)
+	}
+}
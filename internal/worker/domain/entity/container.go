@@ -1,10 +1,17 @@
 package entity
 
+import "time"
+
 type Container struct {
 	ID        string
 	ImageURL  string
 	Status    string
 	Resources ResourceLimits
+	// Execution the container was created for, from its labels
+	ExecutionID string
+	WorkerID    string
+	Attempt     int
+	CreatedAt   time.Time
 }
 
 type ResourceLimits struct {
@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+)
+
+type CacheStore interface {
+	Put(ctx context.Context, entry *entity.CacheEntry) error
+	SetLatestDigest(ctx context.Context, functionID string, digest string) error
+}
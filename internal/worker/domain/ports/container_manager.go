@@ -3,9 +3,16 @@ package ports
 import (
 	"context"
 	"faas/internal/features/executions/domain/entity"
+	workerEntity "faas/internal/worker/domain/entity"
 )
 
 type ContainerManager interface {
+	// RunFunction returns the function stdout. A non-zero exit is reported as
+	// an *ExitError together with the output produced.
 	RunFunction(ctx context.Context, execution *entity.Execution) (string, error)
+	// ListContainers returns the function containers on the host, whichever
+	// worker created them
+	ListContainers(ctx context.Context) ([]*workerEntity.Container, error)
+	RemoveContainer(ctx context.Context, containerID string) error
 	Stop() error
 }
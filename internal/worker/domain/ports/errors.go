@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrExecutionTimeout is returned when a function outlives its deadline
+var ErrExecutionTimeout = errors.New("execution timed out")
+
+// ErrOutputTooLarge is returned when a function writes more than the output limit
+var ErrOutputTooLarge = errors.New("output too large")
+
+// ExitError is returned when a function exits with a non-zero code. The
+// output written before exiting is still returned alongside it.
+type ExitError struct {
+	Code int64
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("function exited with code %d", e.Code)
+}
@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event *entity.ExecutionEvent) error
+}
@@ -0,0 +1,8 @@
+package ports
+
+import "faas/internal/features/executions/domain/entity"
+
+// ExecutionQueue puts executions back in the pending queue
+type ExecutionQueue interface {
+	PublishPending(execution *entity.Execution) error
+}
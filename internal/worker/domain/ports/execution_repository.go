@@ -6,5 +6,11 @@ import (
 )
 
 type ExecutionRepository interface {
+	// GetExecution returns nil if the execution doesn't exist
+	GetExecution(ctx context.Context, executionID string) (*entity.Execution, error)
 	UpdateExecution(ctx context.Context, execution *entity.Execution) error
+	// UpdateProgress sets the progress of a running execution without
+	// touching its other fields. It returns false if the execution is no
+	// longer running and was left as it is.
+	UpdateProgress(ctx context.Context, executionID string, progress *int, message string) (bool, error)
 }
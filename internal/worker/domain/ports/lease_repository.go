@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+)
+
+type LeaseRepository interface {
+	// Acquire returns entity.ErrLeaseLost if another worker already runs
+	// the same attempt
+	Acquire(ctx context.Context, lease *entity.Lease) error
+	// Renew returns entity.ErrLeaseLost once the execution was recovered
+	Renew(ctx context.Context, lease *entity.Lease) error
+	// Release removes the lease if this worker still holds it, reporting
+	// whether it did. A lease already gone means the execution was recovered.
+	Release(ctx context.Context, lease *entity.Lease) (bool, error)
+}
@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+)
+
+type LogRepository interface {
+	Append(ctx context.Context, executionID string, entry *entity.LogEntry) error
+	End(ctx context.Context, executionID string) error
+}
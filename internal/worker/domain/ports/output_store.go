@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+)
+
+// OutputStore keeps outputs too large to be stored inline
+type OutputStore interface {
+	Put(ctx context.Context, executionID string, output []byte) (*entity.OutputRef, error)
+}
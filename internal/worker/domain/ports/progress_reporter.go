@@ -0,0 +1,7 @@
+package ports
+
+// ProgressReporter receives the progress functions report while they run.
+// progress is nil when only the message changed.
+type ProgressReporter interface {
+	Report(executionID string, progress *int, message string)
+}
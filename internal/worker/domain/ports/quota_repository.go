@@ -0,0 +1,7 @@
+package ports
+
+import "context"
+
+type QuotaRepository interface {
+	Release(ctx context.Context, key, executionID string) error
+}
@@ -6,9 +6,14 @@ import (
 )
 
 type StreamConsumer interface {
-	Subscribe(handler func(ctx context.Context, execution *entity.Execution) error) Worker
+	// Subscribe runs handler for each execution with a context derived from
+	// ctx; cancelling ctx cancels the running executions
+	Subscribe(ctx context.Context, handler func(ctx context.Context, execution *entity.Execution) error) Worker
 }
 
 type Worker interface {
+	// Stop stops taking new executions. The running ones carry on.
 	Stop() error
+	// Wait blocks until the running executions have returned
+	Wait()
 }
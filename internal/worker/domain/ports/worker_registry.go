@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+	"faas/internal/features/executions/domain/entity"
+)
+
+type WorkerRegistry interface {
+	Heartbeat(ctx context.Context, worker *entity.Worker) error
+	Deregister(ctx context.Context, workerID string) error
+}
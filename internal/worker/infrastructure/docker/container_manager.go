@@ -3,29 +3,67 @@ package docker
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"faas/internal/features/executions/domain/entity"
+	functionEntity "faas/internal/features/functions/domain/entity"
 	"faas/internal/shared/infrastructure/config"
+	workerEntity "faas/internal/worker/domain/entity"
 	"faas/internal/worker/domain/ports"
+	"faas/internal/worker/infrastructure/environment"
+	"faas/internal/worker/infrastructure/logs"
+	"faas/internal/worker/infrastructure/sandbox"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 )
 
+// Labels of function containers. faas.attempt counts from 1, like FAAS_ATTEMPT.
+const (
+	labelManaged     = "faas.managed"
+	labelExecutionID = "faas.execution_id"
+	labelFunctionID  = "faas.function_id"
+	labelUserID      = "faas.user_id"
+	labelWorkerID    = "faas.worker_id"
+	labelAttempt     = "faas.attempt"
+)
+
 type DockerContainerManager struct {
 	client       *client.Client
 	functionRepo ports.FunctionRepository
 	secretRepo   ports.SecretRepository
+	logRepo      ports.LogRepository
+	progress     ports.ProgressReporter
+	profiles     *sandbox.Profiles
 	config       *config.Config
+
+	logMaxBytes      int
+	logCaptureStdout bool
+	maxOutputBytes   int
+	defaultTimeout   time.Duration
+	stopGracePeriod  time.Duration
+	options          Options
 }
 
-func NewContainerManager(functionRepo ports.FunctionRepository, secretRepo ports.SecretRepository, config *config.Config) (ports.ContainerManager, error) {
+// Options adapt the manager to other runtimes serving the Docker API
+type Options struct {
+	// QualifyImages expands short image names such as "alpine" to
+	// "docker.io/library/alpine", for runtimes that don't resolve them
+	QualifyImages bool
+}
+
+// NewContainerManager runs functions on the Docker daemon of the environment
+// (DOCKER_HOST, or the default socket)
+func NewContainerManager(functionRepo ports.FunctionRepository, secretRepo ports.SecretRepository, logRepo ports.LogRepository, progress ports.ProgressReporter, profiles *sandbox.Profiles, config *config.Config) (ports.ContainerManager, error) {
 	cli, err := client.NewClientWithOpts(
 		client.FromEnv,
 		client.WithVersion("1.46"),
@@ -33,12 +71,27 @@ func NewContainerManager(functionRepo ports.FunctionRepository, secretRepo ports
 	if err != nil {
 		return nil, err
 	}
+	return NewContainerManagerWithClient(cli, Options{}, functionRepo, secretRepo, logRepo, progress, profiles, config), nil
+}
+
+// NewContainerManagerWithClient runs functions through any client speaking
+// the Docker API
+func NewContainerManagerWithClient(cli *client.Client, options Options, functionRepo ports.FunctionRepository, secretRepo ports.SecretRepository, logRepo ports.LogRepository, progress ports.ProgressReporter, profiles *sandbox.Profiles, config *config.Config) ports.ContainerManager {
 	return &DockerContainerManager{
-		client:       cli,
-		functionRepo: functionRepo,
-		secretRepo:   secretRepo,
-		config:       config,
-	}, nil
+		client:           cli,
+		functionRepo:     functionRepo,
+		secretRepo:       secretRepo,
+		logRepo:          logRepo,
+		progress:         progress,
+		profiles:         profiles,
+		config:           config,
+		logMaxBytes:      config.LogMaxBytes,
+		logCaptureStdout: config.LogCaptureStdout,
+		maxOutputBytes:   config.MaxOutputBytes,
+		defaultTimeout:   config.ExecutionTimeout,
+		stopGracePeriod:  config.StopGracePeriod,
+		options:          options,
+	}
 }
 
 func (m *DockerContainerManager) RunFunction(ctx context.Context, execution *entity.Execution) (string, error) {
@@ -48,13 +101,27 @@ func (m *DockerContainerManager) RunFunction(ctx context.Context, execution *ent
 		return "", err
 	}
 
-	// Pull image if needed
-	reader, err := m.client.ImagePull(ctx, function.ImageURL, image.PullOptions{})
-	if err != nil {
-		return "", err
+	// Reruns can pin the exact image the original execution ran with
+	imageRef := function.ImageURL
+	if execution.PinnedImage != "" {
+		imageRef = execution.PinnedImage
+	}
+	if m.options.QualifyImages {
+		imageRef = qualifyImage(imageRef)
 	}
-	defer reader.Close()
-	io.Copy(io.Discard, reader)
+
+	// Pull image if needed. Bare image IDs only exist locally.
+	execution.Record(entity.PhasePullStarted, imageRef)
+	if !strings.HasPrefix(imageRef, "sha256:") {
+		reader, err := m.client.ImagePull(ctx, imageRef, image.PullOptions{})
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		io.Copy(io.Discard, reader)
+	}
+	execution.Record(entity.PhasePullFinished, "")
+	execution.ImageDigest = m.imageDigest(ctx, imageRef)
 
 	// Create container with input as argument
 	var cmd []string
@@ -62,93 +129,324 @@ func (m *DockerContainerManager) RunFunction(ctx context.Context, execution *ent
 		cmd = []string{execution.Input} // Only add input if not empty
 	}
 
-	// Configurar environment variables
-	env := []string{
-		fmt.Sprintf("API_BASE_URL=%s", m.config.APIBaseURL),
+	// The deadline is fixed before the container is created so the function
+	// can read it from its environment. The invoke token lasts as long as the
+	// container may run.
+	timeout := m.executionTimeout(function)
+	deadline := time.Now().Add(timeout)
+	version := execution.ImageDigest
+	if version == "" {
+		version = imageRef
 	}
-
-	// Get secrets , convert input to json , and validate if have secrets and add them to env
-
-	if execution.Input != "" {
-		var input struct {
-			DirectInputs map[string]interface{} `json:"direct_inputs,omitempty"`
-			ObjectInputs map[string]string      `json:"object_inputs,omitempty"`
-			Secrets      []string               `json:"secrets,omitempty"`
-		}
-		if err := json.Unmarshal([]byte(execution.Input), &input); err != nil {
-			return "", err
-		}
-
-		// Process secrets if present
-		if len(input.Secrets) > 0 {
-			for _, secretName := range input.Secrets {
-				secret, err := m.secretRepo.GetByName(ctx, execution.UserID, secretName)
-				if err != nil {
-					return "", err
-				}
-				env = append(env, fmt.Sprintf("%s=%s", secret.Name, secret.Value))
-			}
-		}
+	env, err := environment.Build(ctx, m.secretRepo, m.config, execution, version, deadline, deadline.Add(m.stopGracePeriod))
+	if err != nil {
+		return "", err
 	}
 
+	// The sandbox profile confines the container: its user, a read-only
+	// rootfs with a scratch tmpfs, capabilities, seccomp and OCI runtime
+	profileName, profile := m.profiles.Resolve(function.SandboxProfile)
+	execution.SandboxProfile = profileName
+
 	// Crear configuración del host
 	hostConfig := &container.HostConfig{
-		NetworkMode: container.NetworkMode(m.config.NetworkName), // Usar la misma red definida en docker-compose
+		NetworkMode:    container.NetworkMode(m.config.NetworkName), // Usar la misma red definida en docker-compose
+		ReadonlyRootfs: profile.ReadOnlyRootfs,
+		Tmpfs:          profile.Tmpfs(),
+		CapDrop:        profile.CapDrop(),
+		SecurityOpt:    profile.SecurityOpt(),
+		Runtime:        profile.Runtime,
 	}
 
+	// Each attempt gets its own container name, so a retry never collides
+	// with a container left behind by the previous one. A function command
+	// replaces the image entrypoint, the input still being its last argument.
 	resp, err := m.client.ContainerCreate(ctx, &container.Config{
-		Image: function.ImageURL,
-		Cmd:   cmd,
-		Env:   env,
-	}, hostConfig, nil, nil, execution.ID)
+		Image:      imageRef,
+		Entrypoint: function.Command,
+		Cmd:        cmd,
+		Env:        env,
+		User:       profile.User,
+		Labels:     m.containerLabels(execution),
+	}, hostConfig, nil, nil, fmt.Sprintf("faas-%s-%d", execution.ID, execution.Attempt+1))
 	if err != nil {
 		return "", err
 	}
+	execution.Record(entity.PhaseContainerCreated, resp.ID)
+
+	// The container is removed however the run ends, once its logs and usage
+	// are collected. The reaper removes those a crashed worker leaves behind.
+	defer m.removeContainer(execution.ID, resp.ID)
 
 	// Start container
 	if err := m.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
 		return "", err
 	}
+	execution.Record(entity.PhaseStarted, "")
+	started := time.Now()
 
-	// Create context with timeout
-	execTimeout := 5 * time.Minute // or preferred value
-	ctx, cancel := context.WithTimeout(ctx, execTimeout)
-	defer cancel()
+	// Wait for the container until the deadline. The logs and usage are
+	// followed until the container is gone, including its grace period.
+	runCtx := ctx
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+
+	// Sample resource usage while the container runs; the wall time stops
+	// when it exits, or when the run is abandoned
+	var exited time.Time
+	sampler := &usageSampler{}
+	samplingDone := make(chan struct{})
+	go func() {
+		defer close(samplingDone)
+		if err := m.sampleUsage(watchCtx, resp.ID, sampler); err != nil && watchCtx.Err() == nil {
+			log.Printf("Error sampling usage of execution %s: %v", execution.ID, err)
+		}
+	}()
+	defer func() {
+		if exited.IsZero() {
+			exited = time.Now()
+		}
+		<-samplingDone
+		execution.Usage = sampler.snapshot(exited.Sub(started))
+	}()
+
+	// Follow stderr (and optionally stdout) while the container runs so the
+	// lines can be streamed live. Stored lines outlive the timeout context.
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		if err := m.followLogs(watchCtx, runCtx, execution.ID, resp.ID); err != nil && watchCtx.Err() == nil {
+			log.Printf("Error following logs of execution %s: %v", execution.ID, err)
+		}
+	}()
+	defer func() {
+		cancel()
+		cancelWatch()
+		<-logsDone
+	}()
 
 	// Wait for container to finish
+	var exitCode int64
 	statusCh, errCh := m.client.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
+		if ctx.Err() != nil {
+			return "", m.abandon(runCtx, execution.ID, resp.ID, timeout)
+		}
 		return "", err
-	case <-statusCh:
-		log.Printf("Container %s finished execution", resp.ID)
+	case status := <-statusCh:
+		exited = time.Now()
+		exitCode = status.StatusCode
+		execution.Record(entity.PhaseExited, fmt.Sprintf("exit code %d", exitCode))
+		log.Printf("Container %s finished execution with code %d", resp.ID, exitCode)
 	case <-ctx.Done():
-		return "", fmt.Errorf("execution timed out after %v", execTimeout)
+		return "", m.abandon(runCtx, execution.ID, resp.ID, timeout)
 	}
 
-	// Get logs
+	// The log stream ends once the container has exited
+	<-logsDone
+
+	// Get output
 	out, err := m.client.ContainerLogs(ctx, resp.ID, container.LogsOptions{
 		ShowStdout: true,
-		ShowStderr: true,
 	})
 	if err != nil {
 		return "", err
 	}
 	defer out.Close()
 
-	// Read output and clean control bytes
-	var stdoutBuf bytes.Buffer
-	_, err = stdcopy.StdCopy(&stdoutBuf, io.Discard, out)
+	// Read output and clean control bytes, up to the output limit
+	stdoutBuf := &limitedBuffer{limit: m.maxOutputBytes}
+	_, err = stdcopy.StdCopy(stdoutBuf, io.Discard, out)
 	if err != nil {
 		return "", err
 	}
+	execution.Record(entity.PhaseOutputCollected, fmt.Sprintf("%d bytes", stdoutBuf.Len()))
+	if stdoutBuf.exceeded {
+		return "", fmt.Errorf("%w: more than %d bytes", ports.ErrOutputTooLarge, m.maxOutputBytes)
+	}
 
-	// Cleanup
-	m.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+	if exitCode != 0 {
+		return stdoutBuf.String(), &ports.ExitError{Code: exitCode}
+	}
 
 	return stdoutBuf.String(), nil
 }
 
+// executionTimeout returns the function timeout, or the worker default
+func (m *DockerContainerManager) executionTimeout(function *functionEntity.Function) time.Duration {
+	if timeout, err := time.ParseDuration(function.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return m.defaultTimeout
+}
+
+// abandon stops the container of a run that ended before the function did
+func (m *DockerContainerManager) abandon(runCtx context.Context, executionID string, containerID string, timeout time.Duration) error {
+	m.stopContainer(executionID, containerID)
+	if err := runCtx.Err(); err != nil {
+		return fmt.Errorf("execution cancelled: %w", err)
+	}
+	return fmt.Errorf("%w after %v", ports.ErrExecutionTimeout, timeout)
+}
+
+// removeContainer removes a container with a fresh context, as the run
+// context may be done by now
+func (m *DockerContainerManager) removeContainer(executionID string, containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := m.RemoveContainer(ctx, containerID); err != nil {
+		log.Printf("Error removing container of execution %s: %v", executionID, err)
+	}
+}
+
+func (m *DockerContainerManager) RemoveContainer(ctx context.Context, containerID string) error {
+	return m.client.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true})
+}
+
+// containerLabels tie a container to its execution, so the reaper can tell
+// which containers are still needed
+func (m *DockerContainerManager) containerLabels(execution *entity.Execution) map[string]string {
+	return map[string]string{
+		labelManaged:     "true",
+		labelExecutionID: execution.ID,
+		labelFunctionID:  execution.FunctionID,
+		labelUserID:      execution.UserID,
+		labelWorkerID:    m.config.WorkerID,
+		labelAttempt:     strconv.Itoa(execution.Attempt + 1),
+	}
+}
+
+func (m *DockerContainerManager) ListContainers(ctx context.Context) ([]*workerEntity.Container, error) {
+	list, err := m.client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", labelManaged+"=true")),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]*workerEntity.Container, 0, len(list))
+	for _, c := range list {
+		attempt, _ := strconv.Atoi(c.Labels[labelAttempt])
+		containers = append(containers, &workerEntity.Container{
+			ID:          c.ID,
+			ImageURL:    c.Image,
+			Status:      c.State,
+			ExecutionID: c.Labels[labelExecutionID],
+			WorkerID:    c.Labels[labelWorkerID],
+			Attempt:     attempt - 1,
+			CreatedAt:   time.Unix(c.Created, 0),
+		})
+	}
+	return containers, nil
+}
+
+// stopContainer sends SIGTERM and kills the container if it is still running
+// after the grace period. It doesn't use the run context, which is done by now.
+func (m *DockerContainerManager) stopContainer(executionID string, containerID string) {
+	grace := int(math.Ceil(m.stopGracePeriod.Seconds()))
+	ctx, cancel := context.WithTimeout(context.Background(), m.stopGracePeriod+30*time.Second)
+	defer cancel()
+
+	err := m.client.ContainerStop(ctx, containerID, container.StopOptions{
+		Signal:  "SIGTERM",
+		Timeout: &grace,
+	})
+	if err != nil {
+		log.Printf("Error stopping container of execution %s: %v", executionID, err)
+	}
+}
+
+// followLogs stores the container logs as timestamped lines until the
+// container exits or ctx is done, and passes progress lines on
+func (m *DockerContainerManager) followLogs(ctx context.Context, storeCtx context.Context, executionID string, containerID string) error {
+	out, err := m.client.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: m.logCaptureStdout,
+		ShowStderr: true,
+		Timestamps: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	recorder := logs.NewRecorder(storeCtx, m.logRepo, executionID, m.logMaxBytes, func(progress *int, message string) {
+		m.progress.Report(executionID, progress, message)
+	})
+	stdout := recorder.Writer(entity.LogStreamStdout)
+	stderr := recorder.Writer(entity.LogStreamStderr)
+	defer stdout.Flush()
+	defer stderr.Flush()
+
+	_, err = stdcopy.StdCopy(stdout, stderr, out)
+	return err
+}
+
+// qualifyImage returns the fully qualified form of an image reference, such
+// as "docker.io/library/alpine:3" for "alpine:3". Bare image IDs and
+// references that don't parse are returned as they are.
+func qualifyImage(imageRef string) string {
+	if strings.HasPrefix(imageRef, "sha256:") {
+		return imageRef
+	}
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return imageRef
+	}
+	return named.String()
+}
+
+// imageDigest returns the content-addressable reference the image was pulled
+// by, or "" when it has none, e.g. images built locally. A local image ID is
+// not returned, as no other worker could pull it for a pinned rerun.
+func (m *DockerContainerManager) imageDigest(ctx context.Context, imageRef string) string {
+	inspect, _, err := m.client.ImageInspectWithRaw(ctx, imageRef)
+	if err != nil {
+		log.Printf("Error inspecting image %s: %v", imageRef, err)
+		return ""
+	}
+	return repoDigest(imageRef, inspect.RepoDigests)
+}
+
+// repoDigest picks the digest of imageRef's repository, as an image pushed
+// to several repositories lists a digest for each of them
+func repoDigest(imageRef string, repoDigests []string) string {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return ""
+	}
+	for _, digest := range repoDigests {
+		candidate, err := reference.ParseNormalizedNamed(digest)
+		if err != nil {
+			continue
+		}
+		if candidate.Name() == named.Name() {
+			return digest
+		}
+	}
+	return ""
+}
+
+// limitedBuffer keeps up to limit bytes and silently drops the rest, so the
+// stream is still drained
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.exceeded = true
+		b.Buffer.Write(p[:room])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
 func (m *DockerContainerManager) Stop() error {
 	return m.client.Close()
 }
@@ -0,0 +1,34 @@
+package docker
+
+import "testing"
+
+func TestRepoDigest(t *testing.T) {
+	const (
+		ghcrDigest = "ghcr.io/acme/resize@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		hubDigest  = "acme/resize@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	digests := []string{ghcrDigest, hubDigest}
+
+	tests := []struct {
+		name     string
+		imageRef string
+		digests  []string
+		want     string
+	}{
+		{name: "matching repository", imageRef: "ghcr.io/acme/resize:1.0", digests: digests, want: ghcrDigest},
+		{name: "normalized docker hub name", imageRef: "docker.io/acme/resize:latest", digests: digests, want: hubDigest},
+		{name: "untagged reference", imageRef: "acme/resize", digests: digests, want: hubDigest},
+		{name: "pinned digest", imageRef: hubDigest, digests: digests, want: hubDigest},
+		{name: "other repository", imageRef: "acme/thumbnail:1.0", digests: digests, want: ""},
+		{name: "local image without digests", imageRef: "acme/resize:dev", digests: nil, want: ""},
+		{name: "local image ID", imageRef: "sha256:2222222222222222222222222222222222222222222222222222222222222222", digests: digests, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repoDigest(tt.imageRef, tt.digests); got != tt.want {
+				t.Errorf("repoDigest(%q) = %q, want %q", tt.imageRef, got, tt.want)
+			}
+		})
+	}
+}
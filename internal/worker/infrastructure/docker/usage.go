@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// usageSampler keeps the latest cumulative counters of a container and the
+// highest memory usage seen. Docker samples about once a second and a stopped
+// container has no stats left, so runs shorter than that record no usage and
+// longer ones miss what they used after the last sample.
+type usageSampler struct {
+	mu    sync.Mutex
+	usage entity.ResourceUsage
+}
+
+// sampleUsage follows the stats stream of the container until it stops or
+// ctx is done
+func (m *DockerContainerManager) sampleUsage(ctx context.Context, containerID string, sampler *usageSampler) error {
+	stats, err := m.client.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return err
+	}
+	defer stats.Body.Close()
+
+	decoder := json.NewDecoder(stats.Body)
+	for {
+		var sample container.StatsResponse
+		if err := decoder.Decode(&sample); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		sampler.add(&sample)
+	}
+}
+
+func (s *usageSampler) add(sample *container.StatsResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Samples of a stopped container come back zeroed
+	if sample.CPUStats.CPUUsage.TotalUsage == 0 {
+		return
+	}
+
+	s.usage.CPUSeconds = float64(sample.CPUStats.CPUUsage.TotalUsage) / float64(time.Second)
+	for _, memory := range []uint64{sample.MemoryStats.Usage, sample.MemoryStats.MaxUsage} {
+		if memory > s.usage.PeakMemoryBytes {
+			s.usage.PeakMemoryBytes = memory
+		}
+	}
+
+	var rx, tx uint64
+	for _, network := range sample.Networks {
+		rx += network.RxBytes
+		tx += network.TxBytes
+	}
+	s.usage.NetworkRxBytes = rx
+	s.usage.NetworkTxBytes = tx
+
+	var read, write uint64
+	for _, entry := range sample.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			read += entry.Value
+		case "write":
+			write += entry.Value
+		}
+	}
+	s.usage.BlockReadBytes = read
+	s.usage.BlockWriteBytes = write
+}
+
+// snapshot returns the usage so far with the given wall time
+func (s *usageSampler) snapshot(wallTime time.Duration) *entity.ResourceUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := s.usage
+	usage.WallTimeMs = wallTime.Milliseconds()
+	return &usage
+}
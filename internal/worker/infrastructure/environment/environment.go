@@ -0,0 +1,82 @@
+package environment
+
+import (
+	"context"
+	"encoding/json"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/shared/infrastructure/auth"
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build returns the environment every runtime gives a function: the API base
+// URL, the secrets named in the input, the execution context and an invoke
+// token valid until tokenExpiresAt
+func Build(ctx context.Context, secretRepo ports.SecretRepository, config *config.Config, execution *entity.Execution, version string, deadline time.Time, tokenExpiresAt time.Time) ([]string, error) {
+	env := []string{
+		fmt.Sprintf("API_BASE_URL=%s", config.APIBaseURL),
+	}
+
+	secretEnv, err := secrets(ctx, secretRepo, execution)
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, secretEnv...)
+	env = append(env, contextEnv(execution, version, deadline)...)
+
+	token, err := auth.NewInvokeToken(config.InvokeTokenSecret, config.ConsumerKey, execution.UserID, execution.ID, tokenExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return append(env, "FAAS_API_URL="+config.InvokeAPIURL, "FAAS_TOKEN="+token), nil
+}
+
+// secrets returns the secrets listed in the execution input as NAME=value
+func secrets(ctx context.Context, secretRepo ports.SecretRepository, execution *entity.Execution) ([]string, error) {
+	if execution.Input == "" {
+		return nil, nil
+	}
+
+	var input struct {
+		DirectInputs map[string]interface{} `json:"direct_inputs,omitempty"`
+		ObjectInputs map[string]string      `json:"object_inputs,omitempty"`
+		Secrets      []string               `json:"secrets,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(execution.Input), &input); err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, secretName := range input.Secrets {
+		secret, err := secretRepo.GetByName(ctx, execution.UserID, secretName)
+		if err != nil {
+			return nil, err
+		}
+		// The execution context variables can't be overridden
+		if strings.HasPrefix(secret.Name, "FAAS_") {
+			log.Printf("Skipping secret %s of execution %s: reserved name", secret.Name, execution.ID)
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s=%s", secret.Name, secret.Value))
+	}
+	return env, nil
+}
+
+// contextEnv describes the execution to the function. FAAS_ATTEMPT counts from
+// 1 and FAAS_DEADLINE is when the function receives SIGTERM.
+func contextEnv(execution *entity.Execution, version string, deadline time.Time) []string {
+	return []string{
+		"FAAS_EXECUTION_ID=" + execution.ID,
+		"FAAS_FUNCTION_ID=" + execution.FunctionID,
+		"FAAS_FUNCTION_VERSION=" + version,
+		"FAAS_USER_ID=" + execution.UserID,
+		"FAAS_ATTEMPT=" + strconv.Itoa(execution.Attempt+1),
+		"FAAS_DEADLINE=" + deadline.UTC().Format(time.RFC3339Nano),
+		"FAAS_TRACE_ID=" + execution.TraceIDOrDefault(),
+	}
+}
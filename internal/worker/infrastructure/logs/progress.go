@@ -0,0 +1,48 @@
+package logs
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// ProgressPrefix starts a progress line written by a function to stderr,
+// followed by a JSON object such as {"progress": 45, "message": "Resizing"}
+const ProgressPrefix = "::faas-progress::"
+
+// Longest status message kept, in bytes
+const maxStatusMessage = 256
+
+// ProgressHandler receives the progress reported by a function. progress is
+// nil when the line only updated the message.
+type ProgressHandler func(progress *int, message string)
+
+// parseProgress returns the progress and message of a progress line. ok is
+// false when the line is not a valid progress line.
+func parseProgress(line string) (progress *int, message string, ok bool) {
+	payload, found := strings.CutPrefix(line, ProgressPrefix)
+	if !found {
+		return nil, "", false
+	}
+
+	var report struct {
+		Progress *float64 `json:"progress"`
+		Message  string   `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(payload)), &report); err != nil {
+		return nil, "", false
+	}
+	if report.Progress == nil && report.Message == "" {
+		return nil, "", false
+	}
+
+	if report.Progress != nil {
+		percent := int(math.Round(math.Max(0, math.Min(100, *report.Progress))))
+		progress = &percent
+	}
+	message = report.Message
+	if len(message) > maxStatusMessage {
+		message = strings.ToValidUTF8(message[:maxStatusMessage], "")
+	}
+	return progress, message, true
+}
@@ -0,0 +1,141 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"faas/internal/features/executions/domain/entity"
+	"faas/internal/worker/domain/ports"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Longest line buffered while waiting for its newline. Longer lines are split,
+// so output without newlines can't grow the buffer without bound.
+const maxLineBytes = 64 * 1024
+
+// Recorder stores the log lines of one execution, up to maxBytes in total.
+// Once the cap is reached a single truncation marker is stored and the rest
+// of the output is dropped. Progress lines on stderr go to the progress
+// handler instead and don't count towards the cap.
+type Recorder struct {
+	ctx         context.Context
+	repo        ports.LogRepository
+	executionID string
+	maxBytes    int
+	onProgress  ProgressHandler
+
+	mu        sync.Mutex
+	written   int
+	truncated bool
+}
+
+func NewRecorder(ctx context.Context, repo ports.LogRepository, executionID string, maxBytes int, onProgress ProgressHandler) *Recorder {
+	return &Recorder{
+		ctx:         ctx,
+		repo:        repo,
+		executionID: executionID,
+		maxBytes:    maxBytes,
+		onProgress:  onProgress,
+	}
+}
+
+// Writer returns a writer that records every line written to it under stream.
+// Lines are expected to start with a Docker RFC3339 timestamp.
+func (r *Recorder) Writer(stream string) *LineWriter {
+	lineLimit := maxLineBytes
+	if r.maxBytes > 0 && r.maxBytes < lineLimit {
+		lineLimit = r.maxBytes
+	}
+	return &LineWriter{recorder: r, stream: stream, lineLimit: lineLimit}
+}
+
+// full reports whether the cap was reached and only progress lines still count
+func (r *Recorder) full(stream string) bool {
+	if stream == entity.LogStreamStderr && r.onProgress != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.truncated
+}
+
+func (r *Recorder) record(stream string, line string) {
+	at := time.Now()
+	if stamp, rest, found := strings.Cut(line, " "); found {
+		if parsed, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+			at, line = parsed, rest
+		}
+	}
+
+	if stream == entity.LogStreamStderr && r.onProgress != nil {
+		if progress, message, ok := parseProgress(line); ok {
+			r.onProgress(progress, message)
+			return
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.truncated {
+		return
+	}
+	if r.maxBytes > 0 && r.written+len(line) > r.maxBytes {
+		r.truncated = true
+		line = fmt.Sprintf("[logs truncated after %d bytes]", r.written)
+	}
+	r.written += len(line)
+
+	entry := &entity.LogEntry{Stream: stream, Time: at, Line: line}
+	if err := r.repo.Append(r.ctx, r.executionID, entry); err != nil {
+		log.Printf("Error storing log line of execution %s: %v", r.executionID, err)
+	}
+}
+
+// LineWriter splits what is written to it into lines of at most lineLimit
+// bytes. Once the recorder is full the output is dropped unread.
+type LineWriter struct {
+	recorder  *Recorder
+	stream    string
+	lineLimit int
+	buf       []byte
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	if w.recorder.full(w.stream) {
+		w.buf = nil
+		return len(p), nil
+	}
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			if len(w.buf) < w.lineLimit {
+				break
+			}
+			// No newline within the limit, the line is recorded in parts
+			w.recorder.record(w.stream, string(w.buf[:w.lineLimit]))
+			w.buf = w.buf[w.lineLimit:]
+			if w.recorder.full(w.stream) {
+				w.buf = nil
+				break
+			}
+			continue
+		}
+		w.recorder.record(w.stream, strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush records a trailing line that didn't end with a newline
+func (w *LineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.recorder.record(w.stream, string(w.buf))
+		w.buf = nil
+	}
+}
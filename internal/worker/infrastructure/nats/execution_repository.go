@@ -3,13 +3,23 @@ package nats
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"faas/internal/features/executions/domain/entity"
+	execRepo "faas/internal/features/executions/infrastructure/repository"
 	"faas/internal/shared/infrastructure/nats"
 	"faas/internal/worker/domain/ports"
+	"fmt"
+
+	natspkg "github.com/nats-io/nats.go"
 )
 
+// Attempts at a progress update before giving up on a contended execution
+const progressMaxAttempts = 5
+
 type NatsExecutionRepository struct {
-	kv nats.KeyValue
+	js    nats.JetStreamContext
+	kv    nats.KeyValue
+	index nats.KeyValue
 }
 
 func NewExecutionRepository(js nats.JetStreamContext) (ports.ExecutionRepository, error) {
@@ -17,16 +27,90 @@ func NewExecutionRepository(js nats.JetStreamContext) (ports.ExecutionRepository
 	if err != nil {
 		return nil, err
 	}
+	index, err := js.KeyValue(nats.INDEX_BUCKET)
+	if err != nil {
+		return nil, err
+	}
 	return &NatsExecutionRepository{
-		kv: nats.NewKeyValueAdapter(kv),
+		js:    js,
+		kv:    nats.NewKeyValueAdapter(kv),
+		index: nats.NewKeyValueAdapter(index),
 	}, nil
 }
 
+func (r *NatsExecutionRepository) GetExecution(ctx context.Context, executionID string) (*entity.Execution, error) {
+	entry, err := r.kv.Get(executionID)
+	if err != nil {
+		if errors.Is(err, natspkg.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var execution entity.Execution
+	if err := json.Unmarshal(entry.Value(), &execution); err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
 func (r *NatsExecutionRepository) UpdateExecution(ctx context.Context, execution *entity.Execution) error {
+	// The callback is queued before the final status is stored; the API
+	// sends it once the status is there
+	if execution.IsTerminal() && execution.CallbackURL != "" {
+		if err := nats.EnqueueWebhook(r.js, execution.ID); err != nil {
+			return err
+		}
+	}
+
 	data, err := json.Marshal(execution)
 	if err != nil {
 		return err
 	}
-	_, err = r.kv.Put(execution.ID, data)
-	return err
+	if _, err := r.kv.Put(execution.ID, data); err != nil {
+		return err
+	}
+	// Listings filter on the status in the index entries
+	return execRepo.PutIndexEntries(r.index, execution)
+}
+
+// UpdateProgress rewrites the stored execution only if nobody wrote it in
+// between, so a late progress update can't undo the final result
+func (r *NatsExecutionRepository) UpdateProgress(ctx context.Context, executionID string, progress *int, message string) (bool, error) {
+	for attempt := 0; attempt < progressMaxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		entry, err := r.kv.Get(executionID)
+		if err != nil {
+			return false, err
+		}
+		var execution entity.Execution
+		if err := json.Unmarshal(entry.Value(), &execution); err != nil {
+			return false, err
+		}
+		if execution.Status != entity.StatusRunning {
+			return false, nil
+		}
+
+		if progress != nil {
+			execution.Progress = progress
+		}
+		execution.StatusMessage = message
+
+		data, err := json.Marshal(&execution)
+		if err != nil {
+			return false, err
+		}
+		_, err = r.kv.Update(executionID, data, entry.Revision())
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, natspkg.ErrKeyExists) {
+			return false, err
+		}
+	}
+
+	return false, fmt.Errorf("progress of execution %s: too much contention", executionID)
 }
@@ -3,37 +3,74 @@ package nats
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"faas/internal/features/executions/domain/entity"
+	sharedNats "faas/internal/shared/infrastructure/nats"
 	"faas/internal/worker/domain/ports"
+	"fmt"
 	"log"
 	"math"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 )
 
 const (
-	EXECUTIONS_SUBJECT = "executions.pending"
-	WORKERS_QUEUE      = "execution-workers"
+	WORKERS_QUEUE = "execution-workers"
+
+	// Durable that moves executions queued before priority classes
+	LEGACY_QUEUE = WORKERS_QUEUE + "-legacy"
+
+	// How long a single fetch waits on an empty priority queue
+	fetchWait = 250 * time.Millisecond
+
+	// Most executions taken in a single fetch
+	maxFetchBatch = 10
 )
 
+// prioritySubscription is a pull subscription on one priority class, weighted
+// with smooth weighted round-robin so lower classes are never starved
+type prioritySubscription struct {
+	priority entity.ExecutionPriority
+	weight   int
+	current  int
+	sub      *nats.Subscription
+}
+
+// NatsStreamConsumer runs up to concurrency executions at once. Each free
+// slot of the semaphore allows one more message to be fetched, so messages
+// are never held without a slot to run them in.
 type NatsStreamConsumer struct {
-	js           nats.JetStreamContext
-	subscription *nats.Subscription
+	js            nats.JetStreamContext
+	weights       map[entity.ExecutionPriority]int
+	subscriptions []*prioritySubscription
+	slots         chan struct{}
+	inFlight      sync.WaitGroup
+	done          chan struct{}
+	stopped       chan struct{}
 }
 
-func NewStreamConsumer(js nats.JetStreamContext) ports.StreamConsumer {
-	return &NatsStreamConsumer{js: js}
+func NewStreamConsumer(js nats.JetStreamContext, weights map[entity.ExecutionPriority]int, concurrency int) ports.StreamConsumer {
+	return &NatsStreamConsumer{
+		js:      js,
+		weights: weights,
+		slots:   make(chan struct{}, concurrency),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
 }
 
-func (c *NatsStreamConsumer) Subscribe(handler func(ctx context.Context, execution *entity.Execution) error) ports.Worker {
+func (c *NatsStreamConsumer) Subscribe(ctx context.Context, handler func(ctx context.Context, execution *entity.Execution) error) ports.Worker {
 	maxRetries := 5
 	var stream *nats.StreamInfo
 	var err error
 
 	// Try to connect to stream with retry
 	for i := 0; i < maxRetries; i++ {
-		stream, err = c.js.StreamInfo("EXECUTIONS")
+		stream, err = c.js.StreamInfo(sharedNats.EXECUTIONS_STREAM)
 		if err != nil {
 			waitTime := time.Duration(math.Pow(2, float64(i))) * time.Second
 			log.Printf("Stream not available, retrying in %v seconds... (attempt %d/%d)",
@@ -51,49 +88,255 @@ func (c *NatsStreamConsumer) Subscribe(handler func(ctx context.Context, executi
 		log.Fatalf("Failed to connect to stream after %d attempts: %v", maxRetries, err)
 	}
 
-	// Configure consumer
-	sub, err := c.js.QueueSubscribe(
-		EXECUTIONS_SUBJECT,
-		WORKERS_QUEUE,
-		func(msg *nats.Msg) {
-			log.Printf("Received message: %s", string(msg.Data))
-			var execution entity.Execution
-			if err := json.Unmarshal(msg.Data, &execution); err != nil {
-				log.Printf("Error unmarshaling execution: %v", err)
+	if err := c.requeueLegacy(); err != nil {
+		log.Printf("Error requeueing executions queued before priority classes: %v", err)
+	}
+
+	// Configure one durable pull consumer per priority class
+	for _, priority := range entity.Priorities {
+		sub, err := c.pullSubscribe(priority)
+		if err != nil {
+			log.Fatalf("Error subscribing to %s executions: %v", priority, err)
+		}
+		c.subscriptions = append(c.subscriptions, &prioritySubscription{
+			priority: priority,
+			weight:   c.weights[priority],
+			sub:      sub,
+		})
+		log.Printf("Successfully subscribed to %s with weight %d",
+			sharedNats.PendingSubject(string(priority)), c.weights[priority])
+	}
+
+	log.Printf("Running up to %d executions at once", cap(c.slots))
+	go c.run(ctx, handler)
+	return c
+}
+
+// pullSubscribe binds to the shared durable consumer of a priority class,
+// creating it first so that unsubscribing never deletes it for other workers
+func (c *NatsStreamConsumer) pullSubscribe(priority entity.ExecutionPriority) (*nats.Subscription, error) {
+	durable := WORKERS_QUEUE + "-" + string(priority)
+	subject := sharedNats.PendingSubject(string(priority))
+
+	consumerConfig := &nats.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subject,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       30 * time.Minute,
+		MaxDeliver:    1,
+		DeliverPolicy: nats.DeliverAllPolicy,
+		// Workers only fetch what they have free slots for, so the number of
+		// unacknowledged executions is bounded by their total concurrency
+		MaxAckPending: -1,
+	}
+	if _, err := c.js.AddConsumer(sharedNats.EXECUTIONS_STREAM, consumerConfig); err != nil {
+		if _, err := c.js.UpdateConsumer(sharedNats.EXECUTIONS_STREAM, consumerConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.js.PullSubscribe(subject, durable, nats.Bind(sharedNats.EXECUTIONS_STREAM, durable))
+}
+
+// requeueLegacy moves the executions left on the subject used before priority
+// classes to the normal class. The consumer of the workers before priority
+// classes is deleted first, as a work queue stream takes a single consumer
+// per subject.
+func (c *NatsStreamConsumer) requeueLegacy() error {
+	_, err := c.js.GetLastMsg(sharedNats.EXECUTIONS_STREAM, sharedNats.EXECUTIONS_PENDING_SUBJECT)
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = c.js.DeleteConsumer(sharedNats.EXECUTIONS_STREAM, WORKERS_QUEUE)
+	if err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+		return err
+	}
+
+	consumerConfig := &nats.ConsumerConfig{
+		Durable:       LEGACY_QUEUE,
+		FilterSubject: sharedNats.EXECUTIONS_PENDING_SUBJECT,
+		AckPolicy:     nats.AckExplicitPolicy,
+		DeliverPolicy: nats.DeliverAllPolicy,
+	}
+	if _, err := c.js.AddConsumer(sharedNats.EXECUTIONS_STREAM, consumerConfig); err != nil {
+		return err
+	}
+	sub, err := c.js.PullSubscribe(sharedNats.EXECUTIONS_PENDING_SUBJECT, LEGACY_QUEUE, nats.Bind(sharedNats.EXECUTIONS_STREAM, LEGACY_QUEUE))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	subject := sharedNats.PendingSubject(string(entity.PriorityNormal))
+	requeued := 0
+	for {
+		msgs, err := sub.Fetch(maxFetchBatch, nats.MaxWait(fetchWait))
+		if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if _, err := c.js.Publish(subject, msg.Data); err != nil {
 				msg.Nak()
-				return
+				return err
 			}
+			if err := msg.Ack(); err != nil {
+				log.Printf("Error acknowledging requeued execution: %v", err)
+			}
+			requeued++
+		}
+	}
 
-			if err := handler(context.Background(), &execution); err != nil {
-				log.Printf("Error processing execution: %v", err)
-				msg.Nak()
-				return
+	if requeued > 0 {
+		log.Printf("Requeued %d executions queued before priority classes", requeued)
+	}
+	return nil
+}
+
+func (c *NatsStreamConsumer) run(ctx context.Context, handler func(ctx context.Context, execution *entity.Execution) error) {
+	defer close(c.stopped)
+
+	for {
+		// Wait for a free slot, then take the others that are free too
+		select {
+		case <-c.done:
+			return
+		case c.slots <- struct{}{}:
+		}
+		free := 1
+	take:
+		for free < maxFetchBatch {
+			select {
+			case c.slots <- struct{}{}:
+				free++
+			default:
+				break take
 			}
+		}
 
-			if err := msg.Ack(); err != nil {
-				log.Printf("Error acknowledging message: %v", err)
+		// Start with the class picked by the weighted schedule and fall back
+		// to the others, most urgent first, so idle classes don't waste a turn
+		next := c.next()
+		candidates := []*prioritySubscription{next}
+		for _, s := range c.subscriptions {
+			if s != next {
+				candidates = append(candidates, s)
 			}
+		}
 
-			log.Printf("Successfully processed execution %s", execution.ID)
-		},
-		nats.ManualAck(),
-		nats.AckWait(30*time.Minute),
-		nats.MaxDeliver(1),
-		nats.DeliverAll(),
-	)
+		for _, s := range candidates {
+			if free == 0 {
+				break
+			}
+			msgs, err := s.sub.Fetch(free, nats.MaxWait(fetchWait))
+			if err != nil {
+				if !errors.Is(err, nats.ErrTimeout) && !errors.Is(err, context.DeadlineExceeded) {
+					log.Printf("Error fetching %s executions: %v", s.priority, err)
+				}
+				continue
+			}
+			for _, msg := range msgs {
+				free--
+				c.inFlight.Add(1)
+				go func(msg *nats.Msg) {
+					defer func() {
+						<-c.slots
+						c.inFlight.Done()
+					}()
+					c.handleMessage(ctx, msg, handler)
+				}(msg)
+			}
+		}
 
-	if err != nil {
-		log.Fatalf("Error subscribing to stream: %v", err)
+		// Give back the slots nothing was fetched for
+		for ; free > 0; free-- {
+			<-c.slots
+		}
 	}
+}
 
-	log.Printf("Successfully subscribed to %s with queue %s", EXECUTIONS_SUBJECT, WORKERS_QUEUE)
-	c.subscription = sub
-	return c
+// next picks the priority class whose turn it is (smooth weighted round-robin)
+func (c *NatsStreamConsumer) next() *prioritySubscription {
+	total := 0
+	var best *prioritySubscription
+	for _, s := range c.subscriptions {
+		s.current += s.weight
+		total += s.weight
+		if best == nil || s.current > best.current {
+			best = s
+		}
+	}
+	best.current -= total
+	return best
+}
+
+func (c *NatsStreamConsumer) handleMessage(ctx context.Context, msg *nats.Msg, handler func(ctx context.Context, execution *entity.Execution) error) {
+	log.Printf("Received message: %s", string(msg.Data))
+	var execution entity.Execution
+	if err := json.Unmarshal(msg.Data, &execution); err != nil {
+		log.Printf("Error unmarshaling execution: %v", err)
+		msg.Nak()
+		return
+	}
+
+	if err := handler(ctx, &execution); err != nil {
+		log.Printf("Error processing execution: %v", err)
+		msg.Nak()
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		log.Printf("Error acknowledging message: %v", err)
+	}
+
+	log.Printf("Successfully processed execution %s", execution.ID)
 }
 
+// Stop stops fetching executions. Executions already running carry on.
 func (c *NatsStreamConsumer) Stop() error {
-	if c.subscription != nil {
-		return c.subscription.Unsubscribe()
+	close(c.done)
+	<-c.stopped
+	for _, s := range c.subscriptions {
+		if err := s.sub.Unsubscribe(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// Wait blocks until every execution taken has been handled and acknowledged
+func (c *NatsStreamConsumer) Wait() {
+	c.inFlight.Wait()
+}
+
+// ParsePriorityWeights parses "high=6,normal=3,batch=1". Classes left out get
+// a weight of 1, as every class needs one of at least 1 not to be starved.
+func ParsePriorityWeights(spec string) (map[entity.ExecutionPriority]int, error) {
+	weights := make(map[entity.ExecutionPriority]int, len(entity.Priorities))
+	for _, priority := range entity.Priorities {
+		weights[priority] = 1
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, found := strings.Cut(part, "=")
+		priority := entity.ExecutionPriority(strings.TrimSpace(name))
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || !priority.IsValid() || err != nil || weight < 1 {
+			return nil, fmt.Errorf("invalid priority weight %q", part)
+		}
+		weights[priority] = weight
+	}
+
+	return weights, nil
+}
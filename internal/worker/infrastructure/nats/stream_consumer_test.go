@@ -0,0 +1,99 @@
+package nats
+
+import (
+	"faas/internal/features/executions/domain/entity"
+	"reflect"
+	"testing"
+)
+
+func TestParsePriorityWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[entity.ExecutionPriority]int
+		wantErr bool
+	}{
+		{
+			name: "all classes",
+			spec: "high=6,normal=3,batch=1",
+			want: map[entity.ExecutionPriority]int{entity.PriorityHigh: 6, entity.PriorityNormal: 3, entity.PriorityBatch: 1},
+		},
+		{
+			name: "missing classes default to 1",
+			spec: "high=4",
+			want: map[entity.ExecutionPriority]int{entity.PriorityHigh: 4, entity.PriorityNormal: 1, entity.PriorityBatch: 1},
+		},
+		{
+			name: "spaces are trimmed",
+			spec: " high = 5 , normal=2 ",
+			want: map[entity.ExecutionPriority]int{entity.PriorityHigh: 5, entity.PriorityNormal: 2, entity.PriorityBatch: 1},
+		},
+		{name: "unknown class", spec: "urgent=9", wantErr: true},
+		{name: "zero weight", spec: "high=0", wantErr: true},
+		{name: "invalid weight", spec: "normal=abc", wantErr: true},
+		{name: "negative weight", spec: "batch=-1", wantErr: true},
+		{name: "missing weight", spec: "high=6,garbage", wantErr: true},
+		{
+			name: "empty",
+			spec: "",
+			want: map[entity.ExecutionPriority]int{entity.PriorityHigh: 1, entity.PriorityNormal: 1, entity.PriorityBatch: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePriorityWeights(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePriorityWeights(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePriorityWeights(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[entity.ExecutionPriority]int
+		rounds  int
+		want    map[entity.ExecutionPriority]int
+	}{
+		{
+			name:    "turns follow the weights",
+			weights: map[entity.ExecutionPriority]int{entity.PriorityHigh: 6, entity.PriorityNormal: 3, entity.PriorityBatch: 1},
+			rounds:  10,
+			want:    map[entity.ExecutionPriority]int{entity.PriorityHigh: 6, entity.PriorityNormal: 3, entity.PriorityBatch: 1},
+		},
+		{
+			name:    "equal weights take turns",
+			weights: map[entity.ExecutionPriority]int{entity.PriorityHigh: 1, entity.PriorityNormal: 1, entity.PriorityBatch: 1},
+			rounds:  3,
+			want:    map[entity.ExecutionPriority]int{entity.PriorityHigh: 1, entity.PriorityNormal: 1, entity.PriorityBatch: 1},
+		},
+		{
+			name:    "lowest class is not starved",
+			weights: map[entity.ExecutionPriority]int{entity.PriorityHigh: 100, entity.PriorityNormal: 1, entity.PriorityBatch: 1},
+			rounds:  102,
+			want:    map[entity.ExecutionPriority]int{entity.PriorityHigh: 100, entity.PriorityNormal: 1, entity.PriorityBatch: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &NatsStreamConsumer{}
+			for _, priority := range entity.Priorities {
+				c.subscriptions = append(c.subscriptions, &prioritySubscription{priority: priority, weight: tt.weights[priority]})
+			}
+
+			got := make(map[entity.ExecutionPriority]int)
+			for i := 0; i < tt.rounds; i++ {
+				got[c.next().priority]++
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("turns over %d rounds = %v, want %v", tt.rounds, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package podman
+
+import (
+	"context"
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"faas/internal/worker/infrastructure/docker"
+	"faas/internal/worker/infrastructure/sandbox"
+	"fmt"
+
+	"github.com/docker/docker/client"
+)
+
+// NewContainerManager runs functions on Podman through the Docker-compatible
+// API it serves on its socket (podman system service), so no Docker daemon
+// is needed. The API version is negotiated, as Podman serves an older one,
+// and short image names are qualified, as Podman doesn't resolve them
+// without a registries.conf alias.
+func NewContainerManager(functionRepo ports.FunctionRepository, secretRepo ports.SecretRepository, logRepo ports.LogRepository, progress ports.ProgressReporter, profiles *sandbox.Profiles, config *config.Config) (ports.ContainerManager, error) {
+	cli, err := client.NewClientWithOpts(
+		client.WithHost(config.PodmanSocket),
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail at startup rather than on the first execution
+	if _, err := cli.Ping(context.Background()); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("podman socket %s: %w", config.PodmanSocket, err)
+	}
+
+	options := docker.Options{QualifyImages: true}
+	return docker.NewContainerManagerWithClient(cli, options, functionRepo, secretRepo, logRepo, progress, profiles, config), nil
+}
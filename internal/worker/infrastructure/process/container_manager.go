@@ -0,0 +1,321 @@
+//go:build unix
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"faas/internal/features/executions/domain/entity"
+	functionEntity "faas/internal/features/functions/domain/entity"
+	"faas/internal/shared/infrastructure/config"
+	workerEntity "faas/internal/worker/domain/entity"
+	"faas/internal/worker/domain/ports"
+	"faas/internal/worker/infrastructure/environment"
+	"faas/internal/worker/infrastructure/logs"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// pipeGracePeriod is how long Wait keeps reading the output of a process that
+// exited while something it started still holds stdout or stderr open
+const pipeGracePeriod = 5 * time.Second
+
+// ProcessContainerManager runs functions as local processes instead of
+// containers, for development and integration tests. Each run gets its own
+// temporary working directory and process group, which is signalled as a
+// whole on timeout. There is no isolation from the worker host.
+type ProcessContainerManager struct {
+	functionRepo ports.FunctionRepository
+	secretRepo   ports.SecretRepository
+	logRepo      ports.LogRepository
+	progress     ports.ProgressReporter
+	config       *config.Config
+
+	logMaxBytes      int
+	logCaptureStdout bool
+	maxOutputBytes   int
+	defaultTimeout   time.Duration
+	stopGracePeriod  time.Duration
+	workDir          string
+	functionsDir     string
+}
+
+func NewContainerManager(functionRepo ports.FunctionRepository, secretRepo ports.SecretRepository, logRepo ports.LogRepository, progress ports.ProgressReporter, config *config.Config) (ports.ContainerManager, error) {
+	// Relative paths in function commands are resolved against the functions
+	// directory, the worker's working directory by default
+	functionsDir, err := filepath.Abs(config.ProcessFunctionsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := config.ProcessWorkDir
+	if workDir != "" {
+		if err := os.MkdirAll(workDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ProcessContainerManager{
+		functionRepo:     functionRepo,
+		secretRepo:       secretRepo,
+		logRepo:          logRepo,
+		progress:         progress,
+		config:           config,
+		logMaxBytes:      config.LogMaxBytes,
+		logCaptureStdout: config.LogCaptureStdout,
+		maxOutputBytes:   config.MaxOutputBytes,
+		defaultTimeout:   config.ExecutionTimeout,
+		stopGracePeriod:  config.StopGracePeriod,
+		workDir:          workDir,
+		functionsDir:     functionsDir,
+	}, nil
+}
+
+func (m *ProcessContainerManager) RunFunction(ctx context.Context, execution *entity.Execution) (string, error) {
+	function, err := m.functionRepo.GetByID(ctx, execution.FunctionID)
+	if err != nil {
+		return "", err
+	}
+	if len(function.Command) == 0 {
+		return "", fmt.Errorf("function %s has no command to run as a local process", function.ID)
+	}
+
+	dir, err := os.MkdirTemp(m.workDir, "faas-"+execution.ID+"-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("Error removing working directory of execution %s: %v", execution.ID, err)
+		}
+	}()
+
+	// The input is passed as the last argument, like the container command
+	args := m.resolveCommand(function.Command)
+	if execution.Input != "" {
+		args = append(args, execution.Input)
+	}
+
+	timeout := m.executionTimeout(function)
+	deadline := time.Now().Add(timeout)
+	env, err := environment.Build(ctx, m.secretRepo, m.config, execution, strings.Join(function.Command, " "), deadline, deadline.Add(m.stopGracePeriod))
+	if err != nil {
+		return "", err
+	}
+
+	// Only PATH is inherited from the worker, so interpreters can be found.
+	// HOME and TMPDIR point at the working directory, which is removed after
+	// the run.
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH"), "HOME=" + dir, "TMPDIR=" + dir}, env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.WaitDelay = pipeGracePeriod
+
+	// Stderr (and optionally stdout) lines are stored as they are written.
+	// Stored lines outlive the timeout context.
+	recorder := logs.NewRecorder(ctx, m.logRepo, execution.ID, m.logMaxBytes, func(progress *int, message string) {
+		m.progress.Report(execution.ID, progress, message)
+	})
+	stderr := recorder.Writer(entity.LogStreamStderr)
+	defer stderr.Flush()
+	cmd.Stderr = stderr
+
+	// The group is killed as soon as the output goes over the limit, rather
+	// than left to fill the buffer until it exits
+	overLimit := make(chan struct{})
+	stdoutBuf := &limitedBuffer{limit: m.maxOutputBytes, onExceeded: func() { close(overLimit) }}
+	cmd.Stdout = stdoutBuf
+	if m.logCaptureStdout {
+		stdout := recorder.Writer(entity.LogStreamStdout)
+		defer stdout.Flush()
+		cmd.Stdout = io.MultiWriter(stdoutBuf, stdout)
+	}
+
+	execution.Record(entity.PhaseContainerCreated, dir)
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	execution.Record(entity.PhaseStarted, fmt.Sprintf("pid %d", cmd.Process.Pid))
+	started := time.Now()
+
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+	}()
+
+	runCtx := ctx
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	select {
+	case err = <-waitErr:
+	case <-overLimit:
+		m.signalGroup(execution.ID, cmd.Process.Pid, syscall.SIGKILL)
+		<-waitErr
+		execution.Usage = usage(cmd.ProcessState, time.Since(started))
+		return "", fmt.Errorf("%w: more than %d bytes", ports.ErrOutputTooLarge, m.maxOutputBytes)
+	case <-ctx.Done():
+		m.stopGroup(execution.ID, cmd.Process.Pid, waitErr)
+		execution.Usage = usage(cmd.ProcessState, time.Since(started))
+		if err := runCtx.Err(); err != nil {
+			return "", fmt.Errorf("execution cancelled: %w", err)
+		}
+		return "", fmt.Errorf("%w after %v", ports.ErrExecutionTimeout, timeout)
+	}
+	execution.Usage = usage(cmd.ProcessState, time.Since(started))
+
+	// Whatever the function left running in its group goes with it
+	m.signalGroup(execution.ID, cmd.Process.Pid, syscall.SIGKILL)
+
+	var exitCode int64
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		exitCode = exitStatus(exitErr.ProcessState)
+	case err != nil && !errors.Is(err, exec.ErrWaitDelay):
+		return "", err
+	}
+	execution.Record(entity.PhaseExited, fmt.Sprintf("exit code %d", exitCode))
+	log.Printf("Process %d finished execution with code %d", cmd.Process.Pid, exitCode)
+
+	execution.Record(entity.PhaseOutputCollected, fmt.Sprintf("%d bytes", stdoutBuf.Len()))
+	if stdoutBuf.exceeded {
+		return "", fmt.Errorf("%w: more than %d bytes", ports.ErrOutputTooLarge, m.maxOutputBytes)
+	}
+
+	if exitCode != 0 {
+		return stdoutBuf.String(), &ports.ExitError{Code: exitCode}
+	}
+
+	return stdoutBuf.String(), nil
+}
+
+// resolveCommand makes the elements of a command naming a file under the
+// functions directory absolute, as the process runs in its own directory
+func (m *ProcessContainerManager) resolveCommand(command []string) []string {
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = arg
+		if filepath.IsAbs(arg) || strings.HasPrefix(arg, "-") {
+			continue
+		}
+		path := filepath.Join(m.functionsDir, arg)
+		if _, err := os.Stat(path); err == nil {
+			args[i] = path
+		}
+	}
+	return args
+}
+
+// executionTimeout returns the function timeout, or the worker default
+func (m *ProcessContainerManager) executionTimeout(function *functionEntity.Function) time.Duration {
+	if timeout, err := time.ParseDuration(function.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return m.defaultTimeout
+}
+
+// stopGroup sends SIGTERM to the process group and kills it if the process
+// hasn't exited after the grace period
+func (m *ProcessContainerManager) stopGroup(executionID string, pid int, waitErr <-chan error) {
+	m.signalGroup(executionID, pid, syscall.SIGTERM)
+
+	timer := time.NewTimer(m.stopGracePeriod)
+	defer timer.Stop()
+	select {
+	case <-waitErr:
+		m.signalGroup(executionID, pid, syscall.SIGKILL)
+	case <-timer.C:
+		m.signalGroup(executionID, pid, syscall.SIGKILL)
+		<-waitErr
+	}
+}
+
+func (m *ProcessContainerManager) signalGroup(executionID string, pid int, signal syscall.Signal) {
+	if err := syscall.Kill(-pid, signal); err != nil && !errors.Is(err, syscall.ESRCH) {
+		log.Printf("Error sending %v to the processes of execution %s: %v", signal, executionID, err)
+	}
+}
+
+// ListContainers returns nothing: processes belong to the worker that
+// started them and never outlive a run
+func (m *ProcessContainerManager) ListContainers(ctx context.Context) ([]*workerEntity.Container, error) {
+	return nil, nil
+}
+
+func (m *ProcessContainerManager) RemoveContainer(ctx context.Context, containerID string) error {
+	return nil
+}
+
+func (m *ProcessContainerManager) Stop() error {
+	return nil
+}
+
+// exitStatus returns the exit code of a process, or 128 plus the signal
+// number if it was killed, as a container runtime reports it
+func exitStatus(state *os.ProcessState) int64 {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int64(status.Signal())
+	}
+	return int64(state.ExitCode())
+}
+
+// usage returns the CPU time and peak memory of the process, as reported by
+// the kernel once it exited. Children it didn't wait for aren't counted.
+func usage(state *os.ProcessState, wallTime time.Duration) *entity.ResourceUsage {
+	usage := &entity.ResourceUsage{WallTimeMs: wallTime.Milliseconds()}
+	if state == nil {
+		return usage
+	}
+	usage.CPUSeconds = (state.UserTime() + state.SystemTime()).Seconds()
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok && rusage.Maxrss > 0 {
+		// ru_maxrss is in bytes on Darwin and in kilobytes elsewhere
+		peak := uint64(rusage.Maxrss)
+		if runtime.GOOS != "darwin" {
+			peak *= 1024
+		}
+		usage.PeakMemoryBytes = peak
+	}
+	return usage
+}
+
+// limitedBuffer keeps up to limit bytes and drops the rest, calling
+// onExceeded the first time it does. The buffer isn't embedded, so io.Copy
+// can't bypass the limit through bytes.Buffer.ReadFrom.
+type limitedBuffer struct {
+	buf        bytes.Buffer
+	limit      int
+	exceeded   bool
+	onExceeded func()
+
+	once sync.Once
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.exceeded = true
+		b.buf.Write(p[:room])
+		b.once.Do(b.onExceeded)
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Len() int {
+	return b.buf.Len()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,13 @@
+package runtime
+
+import (
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"faas/internal/worker/infrastructure/podman"
+)
+
+func init() {
+	Register("podman", func(deps *Dependencies, config *config.Config) (ports.ContainerManager, error) {
+		return podman.NewContainerManager(deps.FunctionRepo, deps.SecretRepo, deps.LogRepo, deps.Progress, deps.Sandbox, config)
+	})
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package runtime
+
+import (
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"faas/internal/worker/infrastructure/process"
+)
+
+func init() {
+	Register("process", func(deps *Dependencies, config *config.Config) (ports.ContainerManager, error) {
+		return process.NewContainerManager(deps.FunctionRepo, deps.SecretRepo, deps.LogRepo, deps.Progress, config)
+	})
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"faas/internal/shared/infrastructure/config"
+	"faas/internal/worker/domain/ports"
+	"faas/internal/worker/infrastructure/sandbox"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dependencies are what every runtime needs to run functions
+type Dependencies struct {
+	FunctionRepo ports.FunctionRepository
+	SecretRepo   ports.SecretRepository
+	LogRepo      ports.LogRepository
+	Progress     ports.ProgressReporter
+	Sandbox      *sandbox.Profiles
+}
+
+// Factory creates the container manager of a runtime
+type Factory func(deps *Dependencies, config *config.Config) (ports.ContainerManager, error)
+
+var factories = map[string]Factory{}
+
+// Register makes a runtime available under name. Runtimes register
+// themselves from this package, so build tags can leave some out.
+func Register(name string, factory Factory) {
+	if _, exists := factories[name]; exists {
+		panic("runtime " + name + " registered twice")
+	}
+	factories[name] = factory
+}
+
+// New creates the container manager of the runtime selected by WORKER_RUNTIME
+func New(name string, deps *Dependencies, config *config.Config) (ports.ContainerManager, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown runtime %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(deps, config)
+}
+
+// Names returns the registered runtimes, sorted
+func Names() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,202 @@
+package sandbox
+
+import (
+	_ "embed"
+	"encoding/json"
+	"faas/internal/shared/infrastructure/config"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// restrictiveSeccomp allows the syscalls of ordinary programs and denies
+// those for tracing, namespaces, mounts, kernel keyrings, BPF and io_uring,
+// even if a function regains the capabilities they need
+//
+//go:embed seccomp_restrictive.json
+var restrictiveSeccomp string
+
+// Values of Profile.Seccomp besides the path of a profile file
+const (
+	SeccompRuntimeDefault = ""
+	SeccompUnconfined     = "unconfined"
+	SeccompRestrictive    = "restrictive"
+)
+
+// Profile is how function containers are confined. The zero profile keeps
+// the runtime defaults.
+type Profile struct {
+	// User the function runs as, e.g. "65534:65534". Empty keeps the image user.
+	User string `json:"user,omitempty"`
+	// ReadOnlyRootfs mounts the image read-only
+	ReadOnlyRootfs bool `json:"read_only_rootfs,omitempty"`
+	// TmpfsSize mounts a writable tmpfs of this size at /tmp, e.g. "64m"
+	TmpfsSize string `json:"tmpfs_size,omitempty"`
+	// DropAllCapabilities drops every Linux capability
+	DropAllCapabilities bool `json:"drop_all_capabilities,omitempty"`
+	// NoNewPrivileges stops setuid binaries from gaining privileges
+	NoNewPrivileges bool `json:"no_new_privileges,omitempty"`
+	// Seccomp is "restrictive", "unconfined", the path of a seccomp profile
+	// on the worker, or empty for the runtime default
+	Seccomp string `json:"seccomp,omitempty"`
+	// Runtime is the OCI runtime to use instead of the default, e.g. "runsc"
+	Runtime string `json:"runtime,omitempty"`
+
+	// seccompProfile is the JSON of Seccomp, or "unconfined"
+	seccompProfile string
+}
+
+var builtin = map[string]Profile{
+	"standard": {},
+	"hardened": {
+		User:                "65534:65534",
+		ReadOnlyRootfs:      true,
+		TmpfsSize:           "64m",
+		DropAllCapabilities: true,
+		NoNewPrivileges:     true,
+		Seccomp:             SeccompRestrictive,
+	},
+	"gvisor": {
+		User:                "65534:65534",
+		ReadOnlyRootfs:      true,
+		TmpfsSize:           "64m",
+		DropAllCapabilities: true,
+		NoNewPrivileges:     true,
+		Seccomp:             SeccompRestrictive,
+		Runtime:             "runsc",
+	},
+}
+
+// SecurityOpt returns the Docker security options of the profile
+func (p *Profile) SecurityOpt() []string {
+	var opts []string
+	if p.NoNewPrivileges {
+		opts = append(opts, "no-new-privileges:true")
+	}
+	if p.seccompProfile != "" {
+		opts = append(opts, "seccomp="+p.seccompProfile)
+	}
+	return opts
+}
+
+// CapDrop returns the capabilities the profile drops
+func (p *Profile) CapDrop() []string {
+	if p.DropAllCapabilities {
+		return []string{"ALL"}
+	}
+	return nil
+}
+
+// Tmpfs returns the tmpfs mounts of the profile. The scratch space is
+// writable by any user, as the function may not run as root.
+func (p *Profile) Tmpfs() map[string]string {
+	if p.TmpfsSize == "" {
+		return nil
+	}
+	return map[string]string{"/tmp": "rw,nosuid,nodev,mode=1777,size=" + p.TmpfsSize}
+}
+
+// Profiles are the sandbox profiles functions may choose from
+type Profiles struct {
+	profiles    map[string]*Profile
+	defaultName string
+}
+
+// Load returns the profiles allowed by SANDBOX_PROFILES. Profiles in
+// SANDBOX_PROFILES_FILE, a JSON object of profiles by name, are added to the
+// built-in ones or replace them.
+func Load(config *config.Config) (*Profiles, error) {
+	definitions := make(map[string]Profile, len(builtin))
+	for name, profile := range builtin {
+		definitions[name] = profile
+	}
+	if config.SandboxProfilesFile != "" {
+		data, err := os.ReadFile(config.SandboxProfilesFile)
+		if err != nil {
+			return nil, err
+		}
+		var custom map[string]Profile
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return nil, fmt.Errorf("invalid SANDBOX_PROFILES_FILE: %w", err)
+		}
+		for name, profile := range custom {
+			definitions[name] = profile
+		}
+	}
+
+	profiles := &Profiles{
+		profiles:    make(map[string]*Profile),
+		defaultName: config.SandboxDefaultProfile,
+	}
+	for _, name := range strings.Split(config.SandboxProfiles, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		definition, ok := definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown sandbox profile %q in SANDBOX_PROFILES", name)
+		}
+		seccompProfile, err := loadSeccomp(definition.Seccomp)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox profile %s: %w", name, err)
+		}
+		definition.seccompProfile = seccompProfile
+		profiles.profiles[name] = &definition
+	}
+	if _, ok := profiles.profiles[profiles.defaultName]; !ok {
+		return nil, fmt.Errorf("SANDBOX_DEFAULT_PROFILE %q is not one of SANDBOX_PROFILES", profiles.defaultName)
+	}
+
+	return profiles, nil
+}
+
+// loadSeccomp returns what Docker expects after "seccomp=" for a profile
+func loadSeccomp(seccomp string) (string, error) {
+	switch seccomp {
+	case SeccompRuntimeDefault, SeccompUnconfined:
+		return seccomp, nil
+	case SeccompRestrictive:
+		return restrictiveSeccomp, nil
+	}
+
+	data, err := os.ReadFile(seccomp)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid(data) {
+		return "", fmt.Errorf("seccomp profile %s is not valid JSON", seccomp)
+	}
+	return string(data), nil
+}
+
+// Resolve returns the profile a function runs with. Functions without a
+// profile, or with one the admin no longer allows, get the default.
+func (p *Profiles) Resolve(name string) (string, *Profile) {
+	if name == "" {
+		name = p.defaultName
+	}
+	profile, ok := p.profiles[name]
+	if !ok {
+		log.Printf("Sandbox profile %q is not allowed, using %s", name, p.defaultName)
+		name, profile = p.defaultName, p.profiles[p.defaultName]
+	}
+	return name, profile
+}
+
+// Names returns the allowed profiles, sorted
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.profiles))
+	for name := range p.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default returns the name of the profile of functions that don't choose one
+func (p *Profiles) Default() string {
+	return p.defaultName
+}
@@ -0,0 +1,131 @@
+package sandbox
+
+import (
+	"faas/internal/shared/infrastructure/config"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	seccompFile := writeFile("seccomp.json", `{"defaultAction": "SCMP_ACT_ERRNO"}`)
+	invalidSeccompFile := writeFile("invalid_seccomp.json", `not json`)
+	profilesFile := writeFile("profiles.json", `{
+		"batch": {"user": "1000:1000", "seccomp": "`+seccompFile+`"},
+		"hardened": {"user": "2000:2000", "read_only_rootfs": true}
+	}`)
+	invalidProfilesFile := writeFile("invalid_profiles.json", `{"batch": [}`)
+	badSeccompProfilesFile := writeFile("bad_seccomp_profiles.json", `{"broken": {"seccomp": "`+invalidSeccompFile+`"}}`)
+
+	tests := []struct {
+		name        string
+		profiles    string
+		defaultName string
+		file        string
+		wantNames   []string
+		wantErr     bool
+		check       func(t *testing.T, profiles *Profiles)
+	}{
+		{
+			name:        "hardened only",
+			profiles:    "hardened",
+			defaultName: "hardened",
+			wantNames:   []string{"hardened"},
+			check: func(t *testing.T, profiles *Profiles) {
+				_, profile := profiles.Resolve("")
+				if profile.User != "65534:65534" || !profile.ReadOnlyRootfs || profile.seccompProfile != restrictiveSeccomp {
+					t.Errorf("hardened profile = %+v", profile)
+				}
+			},
+		},
+		{
+			name:        "list with spaces",
+			profiles:    " hardened , standard ,,gvisor",
+			defaultName: "standard",
+			wantNames:   []string{"gvisor", "hardened", "standard"},
+		},
+		{
+			name:        "custom profiles add and replace built-in ones",
+			profiles:    "hardened,batch",
+			defaultName: "hardened",
+			file:        profilesFile,
+			wantNames:   []string{"batch", "hardened"},
+			check: func(t *testing.T, profiles *Profiles) {
+				if _, profile := profiles.Resolve("hardened"); profile.User != "2000:2000" || profile.DropAllCapabilities {
+					t.Errorf("replaced hardened profile = %+v", profile)
+				}
+				if _, profile := profiles.Resolve("batch"); profile.seccompProfile != `{"defaultAction": "SCMP_ACT_ERRNO"}` {
+					t.Errorf("batch seccomp = %q", profile.seccompProfile)
+				}
+			},
+		},
+		{name: "unknown profile", profiles: "hardened,paranoid", defaultName: "hardened", wantErr: true},
+		{name: "default not allowed", profiles: "hardened", defaultName: "standard", wantErr: true},
+		{name: "missing profiles file", profiles: "hardened", defaultName: "hardened", file: filepath.Join(dir, "missing.json"), wantErr: true},
+		{name: "invalid profiles file", profiles: "hardened", defaultName: "hardened", file: invalidProfilesFile, wantErr: true},
+		{name: "invalid seccomp file", profiles: "hardened,broken", defaultName: "hardened", file: badSeccompProfilesFile, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles, err := Load(&config.Config{
+				SandboxProfiles:       tt.profiles,
+				SandboxDefaultProfile: tt.defaultName,
+				SandboxProfilesFile:   tt.file,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load() allowed %v, want an error", profiles.Names())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := profiles.Names(); !slices.Equal(got, tt.wantNames) {
+				t.Errorf("Names() = %v, want %v", got, tt.wantNames)
+			}
+			if profiles.Default() != tt.defaultName {
+				t.Errorf("Default() = %q, want %q", profiles.Default(), tt.defaultName)
+			}
+			if tt.check != nil {
+				tt.check(t, profiles)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	profiles, err := Load(&config.Config{SandboxProfiles: "hardened,standard", SandboxDefaultProfile: "hardened"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    string
+	}{
+		{name: "no profile", profile: "", want: "hardened"},
+		{name: "allowed profile", profile: "standard", want: "standard"},
+		{name: "profile no longer allowed", profile: "gvisor", want: "hardened"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, profile := profiles.Resolve(tt.profile)
+			if name != tt.want || profile != profiles.profiles[tt.want] {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.profile, name, tt.want)
+			}
+		})
+	}
+}
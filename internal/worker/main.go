@@ -5,15 +5,19 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	execRepo "faas/internal/features/executions/infrastructure/repository"
 	funcRepo "faas/internal/features/functions/infrastructure/repository"
 	secretRepoExternal "faas/internal/features/secrets/infrastructure/repository"
 	"faas/internal/shared/infrastructure/config"
 	"faas/internal/shared/infrastructure/nats"
 	"faas/internal/worker/application/service"
-	"faas/internal/worker/infrastructure/docker"
 	workerNats "faas/internal/worker/infrastructure/nats"
+	"faas/internal/worker/infrastructure/runtime"
+	"faas/internal/worker/infrastructure/sandbox"
 )
 
 func main() {
@@ -43,9 +47,32 @@ func main() {
 		log.Fatal("Failed to create secret repository:", err)
 	}
 
-	containerManager, err := docker.NewContainerManager(functionRepo, secretRepo, cfg)
+	logRepo := execRepo.NewNatsExecutionLogRepository(js)
+	eventRepo := execRepo.NewNatsExecutionEventRepository(js)
+
+	outputRepo, err := execRepo.NewNatsOutputRepository(js)
 	if err != nil {
-		log.Fatal("Failed to create container manager:", err)
+		log.Fatal("Failed to create output repository:", err)
+	}
+
+	quotaRepo, err := execRepo.NewNatsQuotaRepository(js)
+	if err != nil {
+		log.Fatal("Failed to create quota repository:", err)
+	}
+
+	leaseRepo, err := execRepo.NewNatsLeaseRepository(js)
+	if err != nil {
+		log.Fatal("Failed to create lease repository:", err)
+	}
+
+	workerRepo, err := execRepo.NewNatsWorkerRepository(js)
+	if err != nil {
+		log.Fatal("Failed to create worker repository:", err)
+	}
+
+	cacheRepo, err := execRepo.NewNatsCacheRepository(js)
+	if err != nil {
+		log.Fatal("Failed to create cache repository:", err)
 	}
 
 	executionRepo, err := workerNats.NewExecutionRepository(js)
@@ -53,24 +80,72 @@ func main() {
 		log.Fatal("Failed to create execution repository:", err)
 	}
 
-	streamConsumer := workerNats.NewStreamConsumer(js)
+	progressService := service.NewProgressService(executionRepo, eventRepo, cfg)
+
+	sandboxProfiles, err := sandbox.Load(cfg)
+	if err != nil {
+		log.Fatal("Failed to load sandbox profiles:", err)
+	}
+	log.Printf("Sandbox profiles: %s (default %s)", strings.Join(sandboxProfiles.Names(), ", "), sandboxProfiles.Default())
+
+	containerManager, err := runtime.New(cfg.WorkerRuntime, &runtime.Dependencies{
+		FunctionRepo: functionRepo,
+		SecretRepo:   secretRepo,
+		LogRepo:      logRepo,
+		Progress:     progressService,
+		Sandbox:      sandboxProfiles,
+	}, cfg)
+	if err != nil {
+		log.Fatal("Failed to create container manager:", err)
+	}
+	log.Printf("Running functions with the %s runtime", cfg.WorkerRuntime)
+
+	priorityWeights, err := workerNats.ParsePriorityWeights(cfg.PriorityWeights)
+	if err != nil {
+		log.Fatalf("Invalid PRIORITY_WEIGHTS: %v", err)
+	}
+	streamConsumer := workerNats.NewStreamConsumer(js, priorityWeights, cfg.WorkerConcurrency)
+	executionQueue := execRepo.NewNatsExecutionStreamRepository(js)
 
 	// Create service
 	executionService := service.NewExecutionService(
 		containerManager,
 		executionRepo,
+		executionQueue,
+		logRepo,
+		eventRepo,
+		outputRepo,
+		quotaRepo,
+		leaseRepo,
+		cacheRepo,
+		progressService,
+		cfg,
 	)
+	heartbeatService := service.NewHeartbeatService(workerRepo, executionService, cfg)
+	containerReaper := service.NewContainerReaperService(containerManager, executionRepo, executionService, cfg)
 
 	log.Println("Starting worker...")
 
+	// Executions run under their own context so a shutdown can let them
+	// finish before cancelling them
+	execCtx, cancelExecutions := context.WithCancel(context.Background())
+	defer cancelExecutions()
+
 	// Configure consumer
-	worker := streamConsumer.Subscribe(executionService.ProcessExecution)
-	log.Println("Subscribed to executions.pending")
+	worker := streamConsumer.Subscribe(execCtx, executionService.ProcessExecution)
+	log.Println("Subscribed to executions.pending.*")
 
 	// Handle graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	heartbeatDone := make(chan struct{})
+	go func() {
+		heartbeatService.Start(ctx)
+		close(heartbeatDone)
+	}()
+	go containerReaper.Start(ctx)
+
 	// Channel for system signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -83,6 +158,30 @@ func main() {
 		log.Println("Context cancelled, shutting down...")
 	}
 
-	// Cleanup
+	// Drain: take no new work and give the running executions the grace
+	// period to finish. The rest are cancelled, which stops their containers
+	// and requeues them for another worker.
 	worker.Stop()
+	drained := make(chan struct{})
+	go func() {
+		worker.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		log.Println("All executions finished")
+	case <-time.After(cfg.DrainGracePeriod):
+		log.Printf("Drain grace period over, requeueing executions: %v", executionService.ActiveExecutions())
+		cancelExecutions()
+		<-drained
+	case <-sigChan:
+		log.Printf("Signal received again, requeueing executions: %v", executionService.ActiveExecutions())
+		cancelExecutions()
+		<-drained
+	}
+
+	// Deregister only once no execution is left
+	cancel()
+	<-heartbeatDone
 }